@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"log"
+	"net/http"
+	"time"
+)
+
+// permissionCacheFlushPath is path of admin endpoint dropping cached permission checks
+const permissionCacheFlushPath = "/permission-cache/flush"
+
+// serveAdmin is function to serve metrics at /debug/vars and operator endpoints, the port must not be exposed publicly
+func (fs *FacilityServer) serveAdmin(port string) {
+	expvar.Publish("permission_cache", expvar.Func(func() interface{} { return fs.permissions.Stats() }))
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc(permissionCacheFlushPath, fs.handleFlushPermissionCache)
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("Failed to serve admin: %v", err)
+	}
+}
+
+// handleFlushPermissionCache is function to drop cached permission checks, such as after roles are changed, and respond with metrics before the flush
+func (fs *FacilityServer) handleFlushPermissionCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := fs.permissions.Stats()
+	fs.permissions.Flush()
+	log.Printf("Permission cache: flushed %d entries", stats.Size)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		log.Println("Permission cache:", err)
+	}
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/teambition/rrule-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	common "onepass.app/facility/hts/common"
+	typing "onepass.app/facility/internal/typing"
+)
+
+// calendarFeedPath is path of iCalendar feeds served over HTTP, a feed is selected by its token as /calendar/<token>.ics
+const calendarFeedPath = "/calendar/"
+
+// calendar feeds cover approved requests from calendarPastDays ago to calendarFutureDays ahead
+const (
+	calendarPastDays   = 30
+	calendarFutureDays = 365
+)
+
+// calendarLayoutTime is layout of UTC date-time in iCalendar
+const calendarLayoutTime = "20060102T150405Z"
+
+// calendarLayoutDate and calendarLayoutLocalTime are layouts of DATE and local DATE-TIME in iCalendar
+const (
+	calendarLayoutDate      = "20060102"
+	calendarLayoutLocalTime = "20060102T150405"
+)
+
+// maxBusyBlocks is the maximum number of busy blocks an iCalendar import can expand into
+const maxBusyBlocks = 5000
+
+// calendarDurationPattern is pattern of iCalendar DURATION value
+var calendarDurationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// calendarMaxLineOctets is maximum length of iCalendar content line before it is folded
+const calendarMaxLineOctets = 75
+
+// serveCalendarFeeds is function to serve iCalendar feeds next to the grpc server
+func (fs *FacilityServer) serveCalendarFeeds(port string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(calendarFeedPath, fs.handleCalendarFeed)
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("Failed to serve calendar feeds: %v", err)
+	}
+}
+
+// handleCalendarFeed is function to respond approved requests of facilities covered by the feed token as iCalendar
+func (fs *FacilityServer) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	token := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, calendarFeedPath), ".ics")
+	feed, facilities, err := fs.dbs.GetCalendarFeedFacilities(ctx, token)
+	if err != nil {
+		if err.Code() == codes.NotFound {
+			http.NotFound(w, r)
+			return
+		}
+		log.Println("Calendar feed:", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	start := now.AddDate(0, 0, -calendarPastDays)
+	finish := now.AddDate(0, 0, calendarFutureDays)
+	eventNames := map[int64]string{}
+	var entries []*CalendarEntry
+	for _, facilityInfo := range facilities {
+		facilityRequests, err := fs.dbs.GetApprovedFacilityRequestList(ctx, facilityInfo.Id, start, finish)
+		if err != nil {
+			log.Println("Calendar feed:", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		for _, facilityRequest := range facilityRequests {
+			entries = append(entries, &CalendarEntry{
+				Request:   facilityRequest,
+				Facility:  facilityInfo,
+				EventName: fs.getCalendarEventName(ctx, eventNames, facilityRequest.EventId),
+			})
+		}
+	}
+
+	name := fmt.Sprintf("Organization %d facilities", feed.OrganizationId)
+	if feed.FacilityId != 0 && len(facilities) != 0 {
+		name = facilities[0].Name
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", "inline; filename=\"facility.ics\"")
+	if _, err := w.Write([]byte(generateCalendar(name, entries, now))); err != nil {
+		log.Println("Calendar feed:", err)
+	}
+}
+
+// getCalendarEventName is function to get name of the event once per feed, the feed is still served when participant service is unavailable
+func (fs *FacilityServer) getCalendarEventName(ctx context.Context, eventNames map[int64]string, eventID int64) string {
+	if name, ok := eventNames[eventID]; ok {
+		return name
+	}
+
+	name := fmt.Sprintf("Event ID: %d", eventID)
+	if event, err := getEvent(ctx, fs.participant, fs.timeouts.Participant, eventID); err == nil && event.Name != "" {
+		name = event.Name
+	}
+	eventNames[eventID] = name
+	return name
+}
+
+// generateCalendar is function to generate RFC 5545 calendar of approved requests, UID of each request is stable across feeds
+func generateCalendar(name string, entries []*CalendarEntry, now time.Time) string {
+	var builder strings.Builder
+	writeCalendarLine(&builder, "BEGIN:VCALENDAR")
+	writeCalendarLine(&builder, "VERSION:2.0")
+	writeCalendarLine(&builder, "PRODID:-//onepass.app//facility//EN")
+	writeCalendarLine(&builder, "CALSCALE:GREGORIAN")
+	writeCalendarLine(&builder, "METHOD:PUBLISH")
+	writeCalendarLine(&builder, "X-WR-CALNAME:"+escapeCalendarText(name))
+	for _, entry := range entries {
+		writeCalendarLine(&builder, "BEGIN:VEVENT")
+		writeCalendarLine(&builder, fmt.Sprintf("UID:facility-request-%d@onepass.app", entry.Request.Id))
+		writeCalendarLine(&builder, "DTSTAMP:"+now.UTC().Format(calendarLayoutTime))
+		writeCalendarLine(&builder, "DTSTART:"+entry.Request.Start.AsTime().UTC().Format(calendarLayoutTime))
+		writeCalendarLine(&builder, "DTEND:"+entry.Request.Finish.AsTime().UTC().Format(calendarLayoutTime))
+		writeCalendarLine(&builder, "SUMMARY:"+escapeCalendarText(entry.EventName))
+		writeCalendarLine(&builder, "LOCATION:"+escapeCalendarText(entry.Facility.Name))
+		writeCalendarLine(&builder, "STATUS:CONFIRMED")
+		writeCalendarLine(&builder, "END:VEVENT")
+	}
+	writeCalendarLine(&builder, "END:VCALENDAR")
+	return builder.String()
+}
+
+// escapeCalendarText is function to escape TEXT value of iCalendar property
+func escapeCalendarText(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return replacer.Replace(text)
+}
+
+// writeCalendarLine is function to write iCalendar content line folded at 75 octets without splitting a character
+func writeCalendarLine(builder *strings.Builder, line string) {
+	limit := calendarMaxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		builder.WriteString(line[:cut])
+		builder.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space of continuation line is counted
+		limit = calendarMaxLineOctets - 1
+	}
+	builder.WriteString(line)
+	builder.WriteString("\r\n")
+}
+
+// unescapeCalendarText is function to unescape TEXT value of iCalendar property
+func unescapeCalendarText(text string) string {
+	replacer := strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+	return replacer.Replace(text)
+}
+
+// parseCalendarLine is function to split iCalendar content line into upper case name, parameters and value
+func parseCalendarLine(line string) (string, map[string]string, string, bool) {
+	colon := -1
+	isQuoted := false
+	for i, c := range line {
+		if c == '"' {
+			isQuoted = !isQuoted
+		}
+		if c == ':' && !isQuoted {
+			colon = i
+			break
+		}
+	}
+	if colon <= 0 {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params := map[string]string{}
+	for _, part := range parts[1:] {
+		if pair := strings.SplitN(part, "=", 2); len(pair) == 2 {
+			params[strings.ToUpper(pair[0])] = strings.Trim(pair[1], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], true
+}
+
+// parseCalendarEvents is function to read VEVENT components of iCalendar, properties of nested components such as VALARM are ignored
+func parseCalendarEvents(content string) ([]CalendarEvent, typing.CustomError) {
+	content = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(content)
+	content = strings.NewReplacer("\n ", "", "\n\t", "").Replace(content)
+
+	var events []CalendarEvent
+	var event CalendarEvent
+	isCalendar := false
+	depth := 0
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, params, value, ok := parseCalendarLine(line)
+		if !ok {
+			return nil, &typing.InputError{Name: "Invalid iCalendar line: " + line}
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case !isCalendar:
+			if name != "BEGIN" || !strings.EqualFold(value, "VCALENDAR") {
+				return nil, &typing.InputError{Name: "Calendar must begin with BEGIN:VCALENDAR"}
+			}
+			isCalendar = true
+		case event == nil:
+			if name == "BEGIN" && strings.EqualFold(value, "VEVENT") {
+				event = CalendarEvent{}
+				depth = 0
+			}
+		case name == "BEGIN":
+			depth++
+		case name == "END" && depth > 0:
+			depth--
+		case name == "END":
+			events = append(events, event)
+			event = nil
+		case depth == 0:
+			event[name] = append(event[name], &CalendarProperty{Params: params, Value: value})
+		}
+	}
+
+	if event != nil {
+		return nil, &typing.InputError{Name: "VEVENT must end with END:VEVENT"}
+	}
+	return events, nil
+}
+
+// first is function to get the first property of the name, nil means the event does not have it
+func (event CalendarEvent) first(name string) *CalendarProperty {
+	if properties := event[name]; len(properties) != 0 {
+		return properties[0]
+	}
+	return nil
+}
+
+// value is function to get value of the first property of the name
+func (event CalendarEvent) value(name string) string {
+	if property := event.first(name); property != nil {
+		return property.Value
+	}
+	return ""
+}
+
+// parseCalendarTime is function to parse DATE or DATE-TIME value, floating time and unknown TZID are in the facility's location
+func parseCalendarTime(value string, params map[string]string, location *time.Location) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(calendarLayoutDate) {
+		date, err := time.ParseInLocation(calendarLayoutDate, value, location)
+		return date, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		dateTime, err := time.Parse(calendarLayoutTime, value)
+		return dateTime, false, err
+	}
+	if zone, err := time.LoadLocation(params["TZID"]); params["TZID"] != "" && err == nil {
+		location = zone
+	}
+	dateTime, err := time.ParseInLocation(calendarLayoutLocalTime, value, location)
+	return dateTime, false, err
+}
+
+// parseCalendarDuration is function to parse iCalendar DURATION value
+func parseCalendarDuration(value string) (time.Duration, bool) {
+	match := calendarDurationPattern.FindStringSubmatch(value)
+	if match == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, false
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var duration time.Duration
+	for i, unit := range units {
+		if match[i+2] != "" {
+			amount, _ := strconv.Atoi(match[i+2])
+			duration += time.Duration(amount) * unit
+		}
+	}
+	if match[1] == "-" {
+		duration = -duration
+	}
+	return duration, true
+}
+
+// parseCalendarBusyBlocks is function to expand events of iCalendar into busy blocks overlapping from until,
+// transparent and cancelled events do not block and RECURRENCE-ID events replace their occurrences of the recurring event
+func parseCalendarBusyBlocks(content string, location *time.Location, from time.Time, until time.Time) ([]*common.BusyBlock, typing.CustomError) {
+	events, err := parseCalendarEvents(content)
+	if err != nil {
+		return nil, err
+	}
+
+	overridden := map[string]bool{}
+	for _, event := range events {
+		if recurrenceID := event.first("RECURRENCE-ID"); recurrenceID != nil {
+			instance, _, parseErr := parseCalendarTime(recurrenceID.Value, recurrenceID.Params, location)
+			if parseErr != nil {
+				return nil, &typing.InputError{Name: "Invalid RECURRENCE-ID: " + recurrenceID.Value}
+			}
+			overridden[event.value("UID")+"/"+instance.UTC().Format(calendarLayoutTime)] = true
+		}
+	}
+
+	var result []*common.BusyBlock
+	for _, event := range events {
+		if strings.EqualFold(event.value("TRANSP"), "TRANSPARENT") || strings.EqualFold(event.value("STATUS"), "CANCELLED") {
+			continue
+		}
+
+		dtstart := event.first("DTSTART")
+		if dtstart == nil {
+			return nil, &typing.InputError{Name: "VEVENT must have DTSTART"}
+		}
+		start, isDate, parseErr := parseCalendarTime(dtstart.Value, dtstart.Params, location)
+		if parseErr != nil {
+			return nil, &typing.InputError{Name: "Invalid DTSTART: " + dtstart.Value}
+		}
+
+		finish := start
+		if dtend := event.first("DTEND"); dtend != nil {
+			if finish, _, parseErr = parseCalendarTime(dtend.Value, dtend.Params, location); parseErr != nil {
+				return nil, &typing.InputError{Name: "Invalid DTEND: " + dtend.Value}
+			}
+		} else if durationValue := event.value("DURATION"); durationValue != "" {
+			duration, ok := parseCalendarDuration(durationValue)
+			if !ok {
+				return nil, &typing.InputError{Name: "Invalid DURATION: " + durationValue}
+			}
+			finish = start.Add(duration)
+		} else if isDate {
+			finish = start.AddDate(0, 0, 1)
+		}
+		// an instant does not block
+		if !finish.After(start) {
+			continue
+		}
+		duration := finish.Sub(start)
+
+		uid := event.value("UID")
+		occurrences := []time.Time{start}
+		isRecurring := event.first("RRULE") != nil && event.first("RECURRENCE-ID") == nil
+		if isRecurring {
+			set, err := expandCalendarRecurrence(event, start, location)
+			if err != nil {
+				return nil, err
+			}
+
+			occurrences = nil
+			next := set.Iterator()
+			for occurrenceStart, ok := next(); ok && occurrenceStart.Before(until); occurrenceStart, ok = next() {
+				if occurrenceStart.Add(duration).After(from) && !overridden[uid+"/"+occurrenceStart.UTC().Format(calendarLayoutTime)] {
+					occurrences = append(occurrences, occurrenceStart)
+				}
+				if len(occurrences) > maxBusyBlocks {
+					break
+				}
+			}
+		}
+
+		for _, occurrenceStart := range occurrences {
+			occurrenceFinish := occurrenceStart.Add(duration)
+			if !occurrenceStart.Before(until) || !occurrenceFinish.After(from) {
+				continue
+			}
+			if len(result) == maxBusyBlocks {
+				return nil, &typing.InputError{Name: fmt.Sprintf("Calendar must not exceed %d busy blocks", maxBusyBlocks)}
+			}
+			result = append(result, &common.BusyBlock{
+				Uid:     uid,
+				Summary: unescapeCalendarText(event.value("SUMMARY")),
+				Start:   timestamppb.New(occurrenceStart),
+				Finish:  timestamppb.New(occurrenceFinish),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// expandCalendarRecurrence is function to build recurrence of the event from RRULE and EXDATE, rules more frequent than hourly are refused
+func expandCalendarRecurrence(event CalendarEvent, start time.Time, location *time.Location) (*rrule.Set, typing.CustomError) {
+	option, err := rrule.StrToROptionInLocation(event.value("RRULE"), start.Location())
+	if err != nil {
+		return nil, &typing.InputError{Name: "Invalid RRULE: " + err.Error()}
+	}
+	if option.Freq == rrule.MINUTELY || option.Freq == rrule.SECONDLY {
+		return nil, &typing.InputError{Name: "RRULE must not be more frequent than HOURLY"}
+	}
+	option.Dtstart = start
+
+	rule, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, &typing.InputError{Name: "Invalid RRULE: " + err.Error()}
+	}
+
+	set := &rrule.Set{}
+	set.RRule(rule)
+	for _, exdate := range event["EXDATE"] {
+		for _, value := range strings.Split(exdate.Value, ",") {
+			excluded, _, err := parseCalendarTime(strings.TrimSpace(value), exdate.Params, location)
+			if err != nil {
+				return nil, &typing.InputError{Name: "Invalid EXDATE: " + value}
+			}
+			set.ExDate(excluded)
+		}
+	}
+
+	return set, nil
+}
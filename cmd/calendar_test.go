@@ -0,0 +1,201 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	common "onepass.app/facility/hts/common"
+)
+
+func TestGenerateCalendar(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2021, time.March, 1, 8, 0, 0, 0, time.UTC)
+	location, _ := time.LoadLocation("Asia/Bangkok")
+	entries := []*CalendarEntry{{
+		Request: &common.FacilityRequest{
+			Id:     42,
+			Start:  timestamppb.New(time.Date(2021, time.March, 2, 9, 0, 0, 0, location)),
+			Finish: timestamppb.New(time.Date(2021, time.March, 2, 12, 0, 0, 0, location)),
+		},
+		Facility:  &common.Facility{Name: "ISE Hall"},
+		EventName: "Freshy night, 2021; part 1",
+	}}
+
+	expected := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//onepass.app//facility//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:ISE Hall",
+		"BEGIN:VEVENT",
+		"UID:facility-request-42@onepass.app",
+		"DTSTAMP:20210301T080000Z",
+		"DTSTART:20210302T020000Z",
+		"DTEND:20210302T050000Z",
+		`SUMMARY:Freshy night\, 2021\; part 1`,
+		"LOCATION:ISE Hall",
+		"STATUS:CONFIRMED",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	assert.Equal(expected, generateCalendar("ISE Hall", entries, now))
+}
+
+func TestEscapeCalendarText(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`a\\b\;c\,d\ne`, escapeCalendarText("a\\b;c,d\ne"))
+}
+
+func TestWriteCalendarLine(t *testing.T) {
+	assert := assert.New(t)
+
+	var builder strings.Builder
+	writeCalendarLine(&builder, "SUMMARY:"+strings.Repeat("ห้อง", 20))
+	lines := strings.Split(strings.TrimSuffix(builder.String(), "\r\n"), "\r\n")
+	assert.Greater(len(lines), 1)
+	unfolded := lines[0]
+	for i, line := range lines {
+		assert.LessOrEqual(len(line), calendarMaxLineOctets)
+		if i != 0 {
+			assert.True(strings.HasPrefix(line, " "))
+			unfolded += line[1:]
+		}
+	}
+	assert.Equal("SUMMARY:"+strings.Repeat("ห้อง", 20), unfolded)
+}
+
+func TestParseCalendarBusyBlocks(t *testing.T) {
+	assert := assert.New(t)
+
+	location, _ := time.LoadLocation("Asia/Bangkok")
+	from := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	until := from.AddDate(0, 0, 14)
+	content := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:utc",
+		"DTSTART:20210302T020000Z",
+		"DTEND:20210302T050000Z",
+		`SUMMARY:Exam\, mid`,
+		" term",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:zoned",
+		"DTSTART;TZID=Asia/Tokyo:20210303T090000",
+		"DURATION:PT1H30M",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:all-day",
+		"DTSTART;VALUE=DATE:20210304",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:free",
+		"DTSTART:20210305T020000Z",
+		"DTEND:20210305T050000Z",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:cancelled",
+		"DTSTART:20210305T020000Z",
+		"DTEND:20210305T050000Z",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:past",
+		"DTSTART:20210201T020000Z",
+		"DTEND:20210201T050000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	result, err := parseCalendarBusyBlocks(content, location, from, until)
+	assert.Nil(err)
+	assert.Equal(3, len(result))
+	assert.Equal("utc", result[0].Uid)
+	assert.Equal("Exam, midterm", result[0].Summary)
+	assert.Equal(time.Date(2021, time.March, 2, 2, 0, 0, 0, time.UTC), result[0].Start.AsTime())
+	assert.Equal(time.Date(2021, time.March, 3, 0, 0, 0, 0, time.UTC), result[1].Start.AsTime())
+	assert.Equal(time.Date(2021, time.March, 3, 1, 30, 0, 0, time.UTC), result[1].Finish.AsTime())
+	assert.Equal(time.Date(2021, time.March, 4, 0, 0, 0, 0, location), result[2].Start.AsTime().In(location))
+	assert.Equal(time.Date(2021, time.March, 5, 0, 0, 0, 0, location), result[2].Finish.AsTime().In(location))
+}
+
+func TestParseCalendarBusyBlocksRecurrence(t *testing.T) {
+	assert := assert.New(t)
+
+	location, _ := time.LoadLocation("Asia/Bangkok")
+	from := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	until := from.AddDate(0, 0, 21)
+	content := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:weekly",
+		"DTSTART:20210222T090000",
+		"DTEND:20210222T100000",
+		"RRULE:FREQ=WEEKLY;COUNT=5",
+		"EXDATE:20210308T090000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:weekly",
+		"RECURRENCE-ID:20210315T090000",
+		"DTSTART:20210315T130000",
+		"DTEND:20210315T140000",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\n")
+
+	result, err := parseCalendarBusyBlocks(content, location, from, until)
+	assert.Nil(err)
+	starts := make([]time.Time, len(result))
+	for i, block := range result {
+		starts[i] = block.Start.AsTime().In(location)
+	}
+	assert.Equal([]time.Time{
+		time.Date(2021, time.March, 1, 9, 0, 0, 0, location),
+		time.Date(2021, time.March, 15, 13, 0, 0, 0, location),
+	}, starts)
+}
+
+func TestParseCalendarBusyBlocksInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	_, err := parseCalendarBusyBlocks("BEGIN:VEVENT\nEND:VEVENT", time.UTC, now, now.AddDate(0, 0, 1))
+	assert.Equal("input error: Calendar must begin with BEGIN:VCALENDAR", err.Error())
+
+	_, err = parseCalendarBusyBlocks("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20210301T090000Z", time.UTC, now, now.AddDate(0, 0, 1))
+	assert.Equal("input error: VEVENT must end with END:VEVENT", err.Error())
+
+	_, err = parseCalendarBusyBlocks("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20210301T090000Z\nDURATION:PT1H\nRRULE:FREQ=MINUTELY\nEND:VEVENT\nEND:VCALENDAR", time.UTC, now, now.AddDate(0, 0, 1))
+	assert.Equal("input error: RRULE must not be more frequent than HOURLY", err.Error())
+}
+
+func TestParseCalendarDuration(t *testing.T) {
+	assert := assert.New(t)
+
+	duration, ok := parseCalendarDuration("P1W2DT3H4M5S")
+	assert.True(ok)
+	assert.Equal(9*24*time.Hour+3*time.Hour+4*time.Minute+5*time.Second, duration)
+
+	duration, ok = parseCalendarDuration("-PT15M")
+	assert.True(ok)
+	assert.Equal(-15*time.Minute, duration)
+
+	_, ok = parseCalendarDuration("P")
+	assert.False(ok)
+	_, ok = parseCalendarDuration("PT")
+	assert.False(ok)
+	_, ok = parseCalendarDuration("1H")
+	assert.False(ok)
+}
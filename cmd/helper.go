@@ -2,79 +2,278 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/timestamp"
 	_ "github.com/lib/pq"
+	"github.com/teambition/rrule-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	account "onepass.app/facility/hts/account"
 	common "onepass.app/facility/hts/common"
 	facility "onepass.app/facility/hts/facility"
 	organizer "onepass.app/facility/hts/organizer"
 	participant "onepass.app/facility/hts/participant"
+	auth "onepass.app/facility/internal/auth"
+	cache "onepass.app/facility/internal/cache"
 	"onepass.app/facility/internal/helper"
+	model "onepass.app/facility/internal/model"
 	typing "onepass.app/facility/internal/typing"
 )
 
-// hasPermission is mock function for account.hasPermission
-func hasPermission(accountClient account.AccountServiceClient, userID int64, organizationID int64, permissionName common.Permission) (bool, typing.CustomError) {
-	in := account.HasPermissionRequest{
-		OrganizationId: organizationID,
-		UserId:         userID,
-		PermissionName: permissionName,
-	}
-	result, err := accountClient.HasPermission(context.Background(), &in)
-	if err != nil {
-		return false, &typing.GRPCError{Name: "Account service"}
+// maxOccurrences is the maximum number of facility requests a recurrence can expand into
+const maxOccurrences = 100
+
+// authenticatedUserID is function to get ID of the user authenticated by the interceptor, handlers act as this user instead of user_id of the request
+func authenticatedUserID(ctx context.Context) (int64, typing.CustomError) {
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok {
+		return 0, &typing.AuthenticationError{Name: "Token is required"}
 	}
-	return result.IsOk, nil
+	return userID, nil
+}
+
+// hasPermission is mock function for account.hasPermission, results are cached in fs.permissions
+func hasPermission(ctx context.Context, fs *FacilityServer, userID int64, organizationID int64, permissionName common.Permission) (bool, typing.CustomError) {
+	key := cache.PermissionKey{UserID: userID, OrganizationID: organizationID, Permission: permissionName}
+	return fs.permissions.Get(ctx, key, func() (bool, typing.CustomError) {
+		// the call is shared by every caller of the key, so it is bounded by its own deadline instead of the caller's
+		callCtx, cancel := context.WithTimeout(context.Background(), fs.timeouts.Account)
+		defer cancel()
+
+		in := account.HasPermissionRequest{
+			OrganizationId: organizationID,
+			UserId:         userID,
+			PermissionName: permissionName,
+		}
+		result, err := fs.account.HasPermission(callCtx, &in)
+		if err != nil {
+			return false, newServiceError("Account service", err)
+		}
+		return result.IsOk, nil
+	})
 }
 
 // hasEvent is mock function for organization.hasEvent
-func hasEvent(oragnizationClient organizer.OrganizationServiceClient, organizationID int64, userID int64, eventID int64) (bool, typing.CustomError) {
+func hasEvent(ctx context.Context, oragnizationClient organizer.OrganizationServiceClient, timeout time.Duration, organizationID int64, userID int64, eventID int64) (bool, typing.CustomError) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	in := organizer.HasEventReq{
 		OrganizationId: organizationID,
 		UserId:         userID,
 		EventId:        eventID,
 	}
-	result, err := oragnizationClient.HasEvent(context.Background(), &in)
+	result, err := oragnizationClient.HasEvent(ctx, &in)
 	if err != nil {
-		return false, &typing.GRPCError{Name: "Organization service"}
+		return false, newServiceError("Organization service", err)
 	}
 	return result.IsOk, nil
 }
 
 // getEvent is mock function for Participant.getEvent
-func getEvent(participantClient participant.ParticipantServiceClient, eventID int64) (*common.Event, typing.CustomError) {
+func getEvent(ctx context.Context, participantClient participant.ParticipantServiceClient, timeout time.Duration, eventID int64) (*common.Event, typing.CustomError) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	in := participant.GetEventRequest{
 		EventId: eventID,
 	}
-	result, err := participantClient.GetEvent(context.Background(), &in)
+	result, err := participantClient.GetEvent(ctx, &in)
 	if err != nil {
-		return nil, &typing.GRPCError{Name: "Participant service"}
+		return nil, newServiceError("Participant service", err)
 	}
 	return result, nil
 }
 
-// isAbleToCreateFacilityRequest is function to check if a facility is able to book according to user psermission
-func isAbleToCreateFacilityRequest(fs *FacilityServer, in *facility.CreateFacilityRequestRequest) (bool, typing.CustomError) {
-	havingPermissionChannel := make(chan bool)
-	eventOwnerChannel := make(chan bool)
-	overlapTimeChannel := make(chan bool)
-	errorChannel := make(chan typing.CustomError)
+// newServiceError is function to report failed call to other service, a call which is cancelled or timed out keeps its code
+func newServiceError(name string, err error) typing.CustomError {
+	switch code := status.Code(err); code {
+	case codes.Canceled, codes.DeadlineExceeded:
+		return &typing.ContextError{Name: name, StatusCode: code}
+	default:
+		return &typing.GRPCError{Name: name}
+	}
+}
+
+// isAbleToCreateFacilityRequest is function to check if a facility is able to book according to user psermission and facility's capacity,
+// the event and the status to create the request with are returned, a booked time is waitlisted when the user joins the waitlist,
+// quota is checked when the request is created
+func isAbleToCreateFacilityRequest(ctx context.Context, fs *FacilityServer, userID int64, in *facility.CreateFacilityRequestRequest) (*common.Event, common.Status, typing.CustomError) {
+	overlapTimeChannel := make(chan bool, 1)
+	errorChannel := make(chan typing.CustomError, 1)
 
 	go func() {
-		isTimeOverlap, err := fs.dbs.IsOverlapTime(in.FacilityId, in.Start, in.End, true)
-		errorChannel <- err
+		isTimeOverlap, err := fs.dbs.IsOverlapTime(ctx, in.FacilityId, in.Start, in.End, true)
+		if err != nil {
+			errorChannel <- err
+		}
 		overlapTimeChannel <- isTimeOverlap
 	}()
 
-	event, err := getEvent(fs.participant, in.EventId)
+	event, err := getEvent(ctx, fs.participant, fs.timeouts.Participant, in.EventId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	facilityInfo, err := fs.dbs.GetFacilityInfo(ctx, in.FacilityId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	isEventOwner, err := isEventOrganizer(ctx, fs, userID, event)
+	isTimeOverlap := <-overlapTimeChannel
+
+	close(errorChannel)
+	for err := range errorChannel {
+		return nil, 0, err
+	}
+	close(overlapTimeChannel)
+
+	if !isEventOwner || err != nil {
+		return nil, 0, err
+	}
+
+	if err := checkCapacity(facilityInfo, event, in.AllowOverCapacity); err != nil {
+		return nil, 0, err
+	}
+
+	status := common.Status_PENDING
+	if isTimeOverlap {
+		if !in.JoinWaitlist {
+			return nil, 0, &typing.AlreadyExistError{Name: "Facility is booked at that time"}
+		}
+		status = common.Status_WAITLISTED
+	}
+
+	return event, status, nil
+}
+
+// checkCapacity is function to refuse an event expecting more attendees than facility's capacity unless it is allowed, unknown capacity or attendance is not checked
+func checkCapacity(facility *common.Facility, event *common.Event, allowOverCapacity bool) typing.CustomError {
+	if allowOverCapacity || facility.Capacity == 0 || event.ExpectedAttendance <= facility.Capacity {
+		return nil
+	}
+
+	return &typing.CapacityError{Capacity: facility.Capacity, Attendance: event.ExpectedAttendance}
+}
+
+// remainingQuota is function to get allowance left of the limit, an exceeded limit has nothing left
+func remainingQuota(limit int64, usage int64) int64 {
+	if usage >= limit {
+		return 0
+	}
+	return limit - usage
+}
+
+// isAbleToGetQuotaUsage is function to check if user organizes events of the requester organization or owns the facility, the facility is returned
+func isAbleToGetQuotaUsage(ctx context.Context, fs *FacilityServer, userID int64, in *facility.GetQuotaUsageRequest) (*common.Facility, typing.CustomError) {
+	facility, err := fs.dbs.GetFacilityInfo(ctx, in.FacilityId)
+	if err != nil {
+		return nil, err
+	}
+
+	isOrganizer, err := hasPermission(ctx, fs, userID, in.OrganizationId, common.Permission_UPDATE_EVENT)
+	if err != nil {
+		return nil, err
+	}
+	if isOrganizer {
+		return facility, nil
+	}
+
+	isPermission, err := hasPermission(ctx, fs, userID, facility.OrganizationId, common.Permission_UPDATE_FACILITY)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isPermission {
+		return nil, &typing.PermissionError{Type: common.Permission_UPDATE_EVENT}
+	}
+
+	return facility, nil
+}
+
+// isAbleToApproveFacilityRequest is function to check if a facility is able to be approved according to user psermission, overlapping is checked when approving
+func isAbleToApproveFacilityRequest(ctx context.Context, fs *FacilityServer, userID int64, in *facility.ApproveFacilityRequestRequest) (bool, typing.CustomError) {
+	facilityRequest, err := fs.dbs.GetFacilityRequest(ctx, in.RequestId)
+	if err != nil {
+		return false, err
+	}
+
+	facility, err := fs.dbs.GetFacilityInfo(ctx, facilityRequest.FacilityId)
+	if err != nil {
+		return false, err
+	}
+
+	// stage of the approver is checked when approving
+	return isFacilityDecider(ctx, fs, userID, facility)
+}
+
+// isAbleToRejectFacilityRequest is function to check if a facility is able to be rejected according to user psermission
+func isAbleToRejectFacilityRequest(ctx context.Context, fs *FacilityServer, userID int64, in *facility.RejectFacilityRequestRequest) (bool, typing.CustomError) {
+	facilityRequest, err := fs.dbs.GetFacilityRequest(ctx, in.RequestId)
+	if err != nil {
+		return false, err
+	}
+
+	facility, err := fs.dbs.GetFacilityInfo(ctx, facilityRequest.FacilityId)
+	if err != nil {
+		return false, err
+	}
+
+	return isFacilityDecider(ctx, fs, userID, facility)
+}
+
+// isFacilityDecider is function to check if user is able to approve or reject requests of the facility,
+// facility with approval chain is decided only by its approvers and facility without one by users having permission to update it
+func isFacilityDecider(ctx context.Context, fs *FacilityServer, userID int64, facility *common.Facility) (bool, typing.CustomError) {
+	if facility.ApprovalChain != nil {
+		if helper.ApproverStage(facility.ApprovalChain, userID) < 0 {
+			return false, &typing.PermissionError{Type: common.Permission_UPDATE_FACILITY}
+		}
+		return true, nil
+	}
+
+	isPermission, err := hasPermission(ctx, fs, userID, facility.OrganizationId, common.Permission_UPDATE_FACILITY)
+	if err != nil {
+		return false, err
+	}
+
+	if !isPermission {
+		return false, &typing.PermissionError{Type: common.Permission_UPDATE_FACILITY}
+	}
+
+	return true, nil
+}
+
+// isAbleToCancelFacilityRequest is function to check if a facility request is able to be cancelled by the event organizer
+func isAbleToCancelFacilityRequest(ctx context.Context, fs *FacilityServer, userID int64, in *facility.CancelFacilityRequestRequest) (bool, typing.CustomError) {
+	facilityRequest, err := fs.dbs.GetFacilityRequest(ctx, in.RequestId)
 	if err != nil {
 		return false, err
 	}
+
+	event, err := getEvent(ctx, fs.participant, fs.timeouts.Participant, facilityRequest.EventId)
+	if err != nil {
+		return false, err
+	}
+
+	return isEventOrganizer(ctx, fs, userID, event)
+}
+
+// isEventOrganizer is function to check if user is able to update the event and the event belongs to user's organization
+func isEventOrganizer(ctx context.Context, fs *FacilityServer, userID int64, event *common.Event) (bool, typing.CustomError) {
+	havingPermissionChannel := make(chan bool)
+	eventOwnerChannel := make(chan bool)
+	errorChannel := make(chan typing.CustomError, 2)
+
 	go func() {
-		result, err := hasPermission(fs.account, in.UserId, event.OrganizationId, common.Permission_UPDATE_EVENT)
+		result, err := hasPermission(ctx, fs, userID, event.OrganizationId, common.Permission_UPDATE_EVENT)
 		if err != nil {
 			errorChannel <- err
 			havingPermissionChannel <- false
@@ -83,7 +282,7 @@ func isAbleToCreateFacilityRequest(fs *FacilityServer, in *facility.CreateFacili
 		havingPermissionChannel <- result
 	}()
 	go func() {
-		result, err := hasEvent(fs.organizer, in.UserId, event.OrganizationId, in.EventId)
+		result, err := hasEvent(ctx, fs.organizer, fs.timeouts.Organizer, event.OrganizationId, userID, event.Id)
 		if err != nil {
 			errorChannel <- err
 			eventOwnerChannel <- false
@@ -93,103 +292,166 @@ func isAbleToCreateFacilityRequest(fs *FacilityServer, in *facility.CreateFacili
 	}()
 
 	isPermission := <-havingPermissionChannel
-	isTimeOverlap := <-overlapTimeChannel
 	isEventOwner := <-eventOwnerChannel
 
 	close(errorChannel)
 	for err := range errorChannel {
 		return false, err
 	}
-
 	close(havingPermissionChannel)
 	close(eventOwnerChannel)
-	close(overlapTimeChannel)
-	close(errorChannel)
 
 	if !(isPermission && isEventOwner) {
 		return false, &typing.PermissionError{Type: common.Permission_UPDATE_EVENT}
 	}
 
-	if isTimeOverlap {
-		return false, &typing.AlreadyExistError{Name: "Facility is booked at that time"}
-	}
-
 	return true, nil
 }
 
-// isAbleToApproveFacilityRequest is function to check if a facility is able to be approved according to user psermission
-func isAbleToApproveFacilityRequest(fs *FacilityServer, in *facility.ApproveFacilityRequestRequest) (bool, typing.CustomError) {
-	facilityRequest, err := fs.dbs.GetFacilityRequest(in.RequestId)
+// isAbleToUpdateFacilityRequestSeries is function to check if occurrences of the series are able to be approved or rejected according to user psermission
+func isAbleToUpdateFacilityRequestSeries(ctx context.Context, fs *FacilityServer, userID int64, seriesID int64) ([]*common.FacilityRequest, typing.CustomError) {
+	facilityRequests, err := fs.dbs.GetFacilityRequestSeries(ctx, seriesID)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	havingPermissionChannel := make(chan bool)
-	overlapTimeChannel := make(chan bool)
-	errorChannel := make(chan typing.CustomError, 2)
+	facility, err := fs.dbs.GetFacilityInfo(ctx, facilityRequests[0].FacilityId)
+	if err != nil {
+		return nil, err
+	}
 
-	go func() {
-		facility, err := fs.dbs.GetFacilityInfo(facilityRequest.FacilityId)
-		if err != nil {
-			errorChannel <- err
-			havingPermissionChannel <- false
-			return
-		}
+	if _, err := isFacilityDecider(ctx, fs, userID, facility); err != nil {
+		return nil, err
+	}
+
+	return facilityRequests, nil
+}
+
+// expandFacilityRequestRecurrence is function to expand recurrence rule into occurrences in the facility's time zone
+func expandFacilityRequestRecurrence(in *facility.CreateRecurringFacilityRequestRequest, location *time.Location) (string, []*common.FacilityRequest, typing.CustomError) {
+	startTime, _ := ptypes.Timestamp(in.Start)
+	finishTime, _ := ptypes.Timestamp(in.End)
+	duration := finishTime.Sub(startTime)
+	if duration <= 0 {
+		return "", nil, &typing.InputError{Name: "Start must be earlier than Finish"}
+	}
 
-		result, err := hasPermission(fs.account, in.UserId, facility.OrganizationId, common.Permission_UPDATE_FACILITY)
+	var option *rrule.ROption
+	if in.Rrule != "" {
+		var err error
+		option, err = rrule.StrToROptionInLocation(strings.TrimPrefix(in.Rrule, "RRULE:"), location)
 		if err != nil {
-			errorChannel <- err
-			havingPermissionChannel <- false
-			return
+			return "", nil, &typing.InputError{Name: "Invalid rrule: " + err.Error()}
 		}
-		havingPermissionChannel <- result
-	}()
+	} else {
+		if in.Until == nil {
+			return "", nil, &typing.InputError{Name: "Until is required when rrule is empty"}
+		}
+		until, _ := ptypes.Timestamp(in.Until)
+		frequency := rrule.DAILY
+		if in.Frequency == facility.Frequency_WEEKLY {
+			frequency = rrule.WEEKLY
+		}
+		option = &rrule.ROption{Freq: frequency, Until: until.In(location)}
+	}
 
-	go func() {
-		isTimeOverlap, err := fs.dbs.IsOverlapTime(facilityRequest.FacilityId, facilityRequest.Start, facilityRequest.Finish, false)
-		if err != nil {
-			errorChannel <- err
-			overlapTimeChannel <- true
-			return
+	if option.Count == 0 && option.Until.IsZero() {
+		return "", nil, &typing.InputError{Name: "Recurrence must end with COUNT or UNTIL"}
+	}
+	option.Dtstart = startTime.In(location)
+
+	rule, err := rrule.NewRRule(*option)
+	if err != nil {
+		return "", nil, &typing.InputError{Name: "Invalid rrule: " + err.Error()}
+	}
+
+	var occurrences []*common.FacilityRequest
+	next := rule.Iterator()
+	for occurrenceStart, ok := next(); ok; occurrenceStart, ok = next() {
+		if len(occurrences) == maxOccurrences {
+			return "", nil, &typing.InputError{Name: fmt.Sprintf("Recurrence must not exceed %d occurrences", maxOccurrences)}
 		}
+		occurrences = append(occurrences, &common.FacilityRequest{
+			EventId:    in.EventId,
+			FacilityId: in.FacilityId,
+			Start:      timestamppb.New(occurrenceStart),
+			Finish:     timestamppb.New(occurrenceStart.Add(duration)),
+		})
+	}
 
-		overlapTimeChannel <- isTimeOverlap
-	}()
+	return option.RRuleString(), occurrences, nil
+}
 
-	isPermission := <-havingPermissionChannel
-	isTimeOverlap := <-overlapTimeChannel
+// isAbleToUpdateFacility is function to check if a facility is able to be updated or deleted according to user psermission
+func isAbleToUpdateFacility(ctx context.Context, fs *FacilityServer, userID int64, facilityID int64) (bool, typing.CustomError) {
+	facility, err := fs.dbs.GetFacilityInfo(ctx, facilityID)
+	if err != nil {
+		return false, err
+	}
 
-	close(errorChannel)
-	for err := range errorChannel {
+	isPermission, err := hasPermission(ctx, fs, userID, facility.OrganizationId, common.Permission_UPDATE_FACILITY)
+	if err != nil {
 		return false, err
 	}
-	close(overlapTimeChannel)
-	close(havingPermissionChannel)
 
 	if !isPermission {
 		return false, &typing.PermissionError{Type: common.Permission_UPDATE_FACILITY}
 	}
 
-	if isTimeOverlap {
-		return false, &typing.AlreadyExistError{Name: "Facility is booked at that time"}
+	return true, nil
+}
+
+// isAbleToImportBusyBlocks is function to check if user owns the facility, the facility is returned for its time zone
+func isAbleToImportBusyBlocks(ctx context.Context, fs *FacilityServer, userID int64, in *facility.ImportBusyBlocksRequest) (*common.Facility, typing.CustomError) {
+	facility, err := fs.dbs.GetFacilityInfo(ctx, in.FacilityId)
+	if err != nil {
+		return nil, err
 	}
 
-	return true, nil
+	isPermission, err := hasPermission(ctx, fs, userID, facility.OrganizationId, common.Permission_UPDATE_FACILITY)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isPermission {
+		return nil, &typing.PermissionError{Type: common.Permission_UPDATE_FACILITY}
+	}
+
+	return facility, nil
 }
 
-// isAbleToRejectFacilityRequest is function to check if a facility is able to be rejected according to user psermission
-func isAbleToRejectFacilityRequest(fs *FacilityServer, in *facility.RejectFacilityRequestRequest) (bool, typing.CustomError) {
-	facilityRequest, err := fs.dbs.GetFacilityRequest(in.RequestId)
+// isAbleToCreateCalendarFeed is function to check if user owns facilities of the feed
+func isAbleToCreateCalendarFeed(ctx context.Context, fs *FacilityServer, userID int64, in *facility.CreateCalendarFeedRequest) (bool, typing.CustomError) {
+	if in.FacilityId != 0 {
+		facilityInfo, err := fs.dbs.GetFacilityInfo(ctx, in.FacilityId)
+		if err != nil {
+			return false, err
+		}
+		if facilityInfo.OrganizationId != in.OrganizationId {
+			return false, &typing.InputError{Name: "Facility is not owned by the organization"}
+		}
+	}
+
+	isPermission, err := hasPermission(ctx, fs, userID, in.OrganizationId, common.Permission_UPDATE_FACILITY)
 	if err != nil {
 		return false, err
 	}
 
-	facility, err := fs.dbs.GetFacilityInfo(facilityRequest.FacilityId)
+	if !isPermission {
+		return false, &typing.PermissionError{Type: common.Permission_UPDATE_FACILITY}
+	}
+
+	return true, nil
+}
+
+// isAbleToRevokeCalendarFeed is function to check if user owns facilities of the feed
+func isAbleToRevokeCalendarFeed(ctx context.Context, fs *FacilityServer, userID int64, in *facility.RevokeCalendarFeedRequest) (bool, typing.CustomError) {
+	feed, err := fs.dbs.GetCalendarFeed(ctx, in.FeedId)
 	if err != nil {
 		return false, err
 	}
 
-	isPermission, err := hasPermission(fs.account, in.UserId, facility.OrganizationId, common.Permission_UPDATE_FACILITY)
+	isPermission, err := hasPermission(ctx, fs, userID, feed.OrganizationId, common.Permission_UPDATE_FACILITY)
 	if err != nil {
 		return false, err
 	}
@@ -201,6 +463,48 @@ func isAbleToRejectFacilityRequest(fs *FacilityServer, in *facility.RejectFacili
 	return true, nil
 }
 
+// isAbleToWatchFacilityRequests is function to check if user is able to watch every filter, event by its organizer and organization or facility by the facility owner
+func isAbleToWatchFacilityRequests(ctx context.Context, fs *FacilityServer, userID int64, in *facility.WatchFacilityRequestsRequest) (bool, typing.CustomError) {
+	if in.EventId == 0 && in.OrganizationId == 0 && in.FacilityId == 0 {
+		return false, &typing.InputError{Name: "At least one of EventId, OrganizationId and FacilityId is required"}
+	}
+
+	if in.EventId != 0 {
+		event, err := getEvent(ctx, fs.participant, fs.timeouts.Participant, in.EventId)
+		if err != nil {
+			return false, err
+		}
+		if _, err := isEventOrganizer(ctx, fs, userID, event); err != nil {
+			return false, err
+		}
+	}
+
+	if in.OrganizationId != 0 {
+		isPermission, err := hasPermission(ctx, fs, userID, in.OrganizationId, common.Permission_UPDATE_FACILITY)
+		if err != nil {
+			return false, err
+		}
+		if !isPermission {
+			return false, &typing.PermissionError{Type: common.Permission_UPDATE_FACILITY}
+		}
+	}
+
+	if in.FacilityId != 0 {
+		if _, err := isAbleToUpdateFacility(ctx, fs, userID, in.FacilityId); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// isMatchingFacilityRequestChange is function to check if the change matches every filter of the watcher
+func isMatchingFacilityRequestChange(in *facility.WatchFacilityRequestsRequest, change *model.FacilityRequestChange) bool {
+	return (in.EventId == 0 || in.EventId == change.EventID) &&
+		(in.OrganizationId == 0 || in.OrganizationId == change.OrganizationID) &&
+		(in.FacilityId == 0 || in.FacilityId == change.FacilityID)
+}
+
 func handlePermissionChannel(permissionEventChannel <-chan bool, permissionFacilityChannel <-chan bool) (bool, common.Permission, typing.CustomError) {
 	var isPermissionEvent bool
 	for i := 0; i < 2; i++ {
@@ -224,8 +528,8 @@ func handlePermissionChannel(permissionEventChannel <-chan bool, permissionFacil
 }
 
 // isAbleToViewFacilityRequest a function to check whether user can view the targed facility request
-func isAbleToViewFacilityRequest(fs *FacilityServer, userID int64, facilityRequest *common.FacilityRequest) (bool, common.Permission, typing.CustomError) {
-	facility, err := fs.dbs.GetFacilityInfo(facilityRequest.FacilityId)
+func isAbleToViewFacilityRequest(ctx context.Context, fs *FacilityServer, userID int64, facilityRequest *common.FacilityRequest) (bool, common.Permission, typing.CustomError) {
+	facility, err := fs.dbs.GetFacilityInfo(ctx, facilityRequest.FacilityId)
 	if err != nil {
 		return false, 0, err
 	}
@@ -235,13 +539,13 @@ func isAbleToViewFacilityRequest(fs *FacilityServer, userID int64, facilityReque
 	errorChannel := make(chan typing.CustomError)
 
 	go func() {
-		event, err := getEvent(fs.participant, facilityRequest.EventId)
+		event, err := getEvent(ctx, fs.participant, fs.timeouts.Participant, facilityRequest.EventId)
 		if err != nil {
 			errorChannel <- err
 			permissionEventChannel <- false
 			return
 		}
-		result, err := hasPermission(fs.account, userID, event.OrganizationId, common.Permission_UPDATE_EVENT)
+		result, err := hasPermission(ctx, fs, userID, event.OrganizationId, common.Permission_UPDATE_EVENT)
 		if err != nil {
 			errorChannel <- err
 			permissionEventChannel <- false
@@ -250,7 +554,7 @@ func isAbleToViewFacilityRequest(fs *FacilityServer, userID int64, facilityReque
 		permissionEventChannel <- result
 	}()
 	go func() {
-		result, err := hasPermission(fs.account, userID, facility.OrganizationId, common.Permission_UPDATE_FACILITY)
+		result, err := hasPermission(ctx, fs, userID, facility.OrganizationId, common.Permission_UPDATE_FACILITY)
 		if err != nil {
 			errorChannel <- err
 			permissionFacilityChannel <- false
@@ -270,8 +574,8 @@ func isAbleToViewFacilityRequest(fs *FacilityServer, userID int64, facilityReque
 }
 
 // isAbleToViewFacilityRequestFull a function to check whether user can view the targed facility request
-func isAbleToViewFacilityRequestFull(fs *FacilityServer, userID int64, facilityRequestFull *facility.FacilityRequestWithFacilityInfo) (bool, common.Permission, typing.CustomError) {
-	event, err := getEvent(fs.participant, facilityRequestFull.EventId)
+func isAbleToViewFacilityRequestFull(ctx context.Context, fs *FacilityServer, userID int64, facilityRequestFull *facility.FacilityRequestWithFacilityInfo) (bool, common.Permission, typing.CustomError) {
+	event, err := getEvent(ctx, fs.participant, fs.timeouts.Participant, facilityRequestFull.EventId)
 	for err != nil {
 		return false, 0, err
 	}
@@ -280,7 +584,7 @@ func isAbleToViewFacilityRequestFull(fs *FacilityServer, userID int64, facilityR
 	errorChannel := make(chan typing.CustomError)
 
 	go func() {
-		result, err := hasPermission(fs.account, userID, event.OrganizationId, common.Permission_UPDATE_EVENT)
+		result, err := hasPermission(ctx, fs, userID, event.OrganizationId, common.Permission_UPDATE_EVENT)
 		if err != nil {
 			errorChannel <- err
 			permissionEventChannel <- false
@@ -289,7 +593,7 @@ func isAbleToViewFacilityRequestFull(fs *FacilityServer, userID int64, facilityR
 		permissionEventChannel <- result
 	}()
 	go func() {
-		result, err := hasPermission(fs.account, userID, facilityRequestFull.OrganizationId, common.Permission_UPDATE_FACILITY)
+		result, err := hasPermission(ctx, fs, userID, facilityRequestFull.OrganizationId, common.Permission_UPDATE_FACILITY)
 		if err != nil {
 			errorChannel <- err
 			permissionFacilityChannel <- false
@@ -308,15 +612,15 @@ func isAbleToViewFacilityRequestFull(fs *FacilityServer, userID int64, facilityR
 	return result, permission, err
 }
 
-// isAbleToGetAvailableTimeOfFacility a function to check whether user can check facility availability
-func isAbleToGetAvailableTimeOfFacility(startTime time.Time, finishTime time.Time) typing.CustomError {
+// isAbleToGetAvailableTimeOfFacility a function to check whether user can check facility availability, start and finish must be in the facility's time zone
+func isAbleToGetAvailableTimeOfFacility(startTime time.Time, finishTime time.Time, window *common.BookingWindow) typing.CustomError {
 	if helper.DayDifference(startTime, finishTime)+1 <= 0 {
 		return &typing.InputError{Name: "Start must be earlier than Finish"}
 	}
 
-	now := time.Now()
-	if helper.DayDifference(now, finishTime) >= 30 {
-		return &typing.InputError{Name: "Booking date can only be within 30 days period from today"}
+	now := time.Now().In(startTime.Location())
+	if err := helper.CheckAdvanceWindow(now, finishTime, window); err != nil {
+		return err
 	}
 
 	dayDifference := helper.DayDifference(now, startTime)
@@ -327,22 +631,23 @@ func isAbleToGetAvailableTimeOfFacility(startTime time.Time, finishTime time.Tim
 	return nil
 }
 
-// createResultEmptyArray is function to create 2D empy boolean array according to input
-func createResultEmptyArray(startTime time.Time, finishTime time.Time, operatingHours map[int32]*common.OperatingHour) []*facility.GetAvailableTimeOfFacilityResponse_Day {
+// createResultEmptyArray is function to create 2D empy boolean array according to input, one item per slot
+func createResultEmptyArray(startTime time.Time, finishTime time.Time, operatingHours []*common.OperatingHour, overrides map[string]*common.OperatingHourOverride, slotMinutes int64) []*facility.GetAvailableTimeOfFacilityResponse_Day {
 	dayDifference := helper.DayDifference(startTime, finishTime) + 1
 	result := make([]*facility.GetAvailableTimeOfFacilityResponse_Day, dayDifference)
+	slotPerHour := 60 / helper.SlotMinutesOrDefault(slotMinutes)
 	var currentDay time.Time
 	for i := range result {
 		currentDay = startTime.AddDate(0, 0, i)
-		operationHour := operatingHours[int32(currentDay.Weekday())]
+		operationHour := helper.OperatingHourOfDay(currentDay, operatingHours, overrides)
 		if operationHour == nil {
 			result[i] = &facility.GetAvailableTimeOfFacilityResponse_Day{Items: nil}
 			continue
 		}
 		startHour := operationHour.StartHour
 		finishHour := operationHour.FinishHour
-		hour := finishHour - startHour
-		avaialbleTime := make([]bool, hour)
+		slot := (finishHour - startHour) * slotPerHour
+		avaialbleTime := make([]bool, slot)
 		for j := range avaialbleTime {
 			avaialbleTime[j] = true
 		}
@@ -353,60 +658,179 @@ func createResultEmptyArray(startTime time.Time, finishTime time.Time, operating
 }
 
 // generateFacilityAvailabilityResult is a function to genereate facility request from empty 2D boolean array
-func generateFacilityAvailabilityResult(resultArray []*facility.GetAvailableTimeOfFacilityResponse_Day, startTime time.Time, operatingHours map[int32]*common.OperatingHour, facilityRequests []*common.FacilityRequest) *facility.GetAvailableTimeOfFacilityResponse {
+func generateFacilityAvailabilityResult(resultArray []*facility.GetAvailableTimeOfFacilityResponse_Day, startTime time.Time, operatingHours []*common.OperatingHour, overrides map[string]*common.OperatingHourOverride, facilityRequests []*common.FacilityRequest, busyBlocks []*common.BusyBlock, slotMinutes int64) *facility.GetAvailableTimeOfFacilityResponse {
+	slotMinutes = helper.SlotMinutesOrDefault(slotMinutes)
+	slot := time.Duration(slotMinutes) * time.Minute
+	// busy blocks occupy slots the same way as approved requests
+	ranges := make([][2]*timestamp.Timestamp, 0, len(facilityRequests)+len(busyBlocks))
 	for _, request := range facilityRequests {
-		requestStartTime, _ := ptypes.Timestamp(request.Start)
-		requestFinishTime, _ := ptypes.Timestamp(request.Finish)
-		index := requestStartTime.Day() - startTime.Day()
-		operatiingHour := operatingHours[int32(requestStartTime.Weekday())]
-		if operatiingHour == nil {
+		ranges = append(ranges, [2]*timestamp.Timestamp{request.Start, request.Finish})
+	}
+	for _, block := range busyBlocks {
+		ranges = append(ranges, [2]*timestamp.Timestamp{block.Start, block.Finish})
+	}
+	for _, occupied := range ranges {
+		requestStartTime, _ := ptypes.Timestamp(occupied[0])
+		requestFinishTime, _ := ptypes.Timestamp(occupied[1])
+
+		// a request can span several days, so it is marked on every day it covers
+		for index := range resultArray {
+			currentDay := time.Date(startTime.Year(), startTime.Month(), startTime.Day()+index, 0, 0, 0, 0, startTime.Location())
+			operatiingHour := helper.OperatingHourOfDay(currentDay, operatingHours, overrides)
+			if operatiingHour == nil {
+				continue
+			}
+			opening := time.Date(currentDay.Year(), currentDay.Month(), currentDay.Day(), int(operatiingHour.StartHour), 0, 0, 0, currentDay.Location())
+			for i := range resultArray[index].Items {
+				currentSlot := opening.Add(time.Duration(i) * slot)
+				if currentSlot.Before(requestFinishTime) && currentSlot.Add(slot).After(requestStartTime) {
+					resultArray[index].Items[i] = false
+				}
+			}
+		}
+	}
+
+	return &facility.GetAvailableTimeOfFacilityResponse{Day: resultArray, SlotMinutes: slotMinutes}
+}
+
+// generateFacilityFreeBusyResult is a function to split every day from startTime to finishTime into free, busy, closed and past intervals
+func generateFacilityFreeBusyResult(startTime time.Time, finishTime time.Time, now time.Time, operatingHours []*common.OperatingHour, overrides map[string]*common.OperatingHourOverride, facilityRequests []*common.FacilityRequest, busyBlocks []*common.BusyBlock) []*facility.GetAvailableTimeOfFacilityResponse_Interval {
+	var result []*facility.GetAvailableTimeOfFacilityResponse_Interval
+	appendInterval := func(start time.Time, end time.Time, status facility.GetAvailableTimeOfFacilityResponse_Interval_Status, requestID int64, busyBlockID int64) {
+		if !start.Before(end) {
+			return
+		}
+		// adjacent intervals of the same kind are merged, also across days
+		if last := len(result) - 1; last >= 0 && result[last].Status == status && result[last].RequestId == requestID && result[last].BusyBlockId == busyBlockID && result[last].End.AsTime().Equal(start) {
+			result[last].End = timestamppb.New(end)
+			return
+		}
+		result = append(result, &facility.GetAvailableTimeOfFacilityResponse_Interval{
+			Start:       timestamppb.New(start),
+			End:         timestamppb.New(end),
+			Status:      status,
+			RequestId:   requestID,
+			BusyBlockId: busyBlockID,
+		})
+	}
+
+	dayDifference := helper.DayDifference(startTime, finishTime)
+	for index := 0; index <= dayDifference; index++ {
+		currentDay := time.Date(startTime.Year(), startTime.Month(), startTime.Day()+index, 0, 0, 0, 0, startTime.Location())
+		nextDay := time.Date(startTime.Year(), startTime.Month(), startTime.Day()+index+1, 0, 0, 0, 0, startTime.Location())
+		operatingHour := helper.OperatingHourOfDay(currentDay, operatingHours, overrides)
+		if operatingHour == nil {
+			appendInterval(currentDay, nextDay, facility.GetAvailableTimeOfFacilityResponse_Interval_CLOSED, 0, 0)
 			continue
 		}
-		startHour := operatiingHour.StartHour
-		requestStartHour := requestStartTime.Hour()
-		requestFinishHour := requestFinishTime.Hour()
-		for i, item := range resultArray[index].Items {
-			currentHour := int(startHour) + i
-			if item && currentHour <= requestStartHour || currentHour >= requestFinishHour {
-				resultArray[index].Items[i] = false
+
+		// hours are built on the calendar so days when daylight saving time changes open on the wall clock
+		opening := time.Date(currentDay.Year(), currentDay.Month(), currentDay.Day(), int(operatingHour.StartHour), 0, 0, 0, currentDay.Location())
+		closing := time.Date(currentDay.Year(), currentDay.Month(), currentDay.Day(), int(operatingHour.FinishHour), 0, 0, 0, currentDay.Location())
+		appendInterval(currentDay, opening, facility.GetAvailableTimeOfFacilityResponse_Interval_CLOSED, 0, 0)
+
+		// the status can only change at now or at an edge of a request or busy block, so opening hours are cut there
+		boundaries := []time.Time{opening, closing}
+		if now.After(opening) && now.Before(closing) {
+			boundaries = append(boundaries, now)
+		}
+		edges := make([]*timestamp.Timestamp, 0, 2*(len(facilityRequests)+len(busyBlocks)))
+		for _, request := range facilityRequests {
+			edges = append(edges, request.Start, request.Finish)
+		}
+		for _, block := range busyBlocks {
+			edges = append(edges, block.Start, block.Finish)
+		}
+		for _, edge := range edges {
+			if edgeTime := edge.AsTime(); edgeTime.After(opening) && edgeTime.Before(closing) {
+				boundaries = append(boundaries, edgeTime)
+			}
+		}
+		sort.Slice(boundaries, func(i, j int) bool { return boundaries[i].Before(boundaries[j]) })
+
+		for i := 0; i < len(boundaries)-1; i++ {
+			start, end := boundaries[i], boundaries[i+1]
+			switch requestID, busyBlockID := findBlockingRequest(facilityRequests, start), findBlockingBusyBlock(busyBlocks, start); {
+			case !end.After(now):
+				appendInterval(start, end, facility.GetAvailableTimeOfFacilityResponse_Interval_PAST, 0, 0)
+			case requestID != 0:
+				appendInterval(start, end, facility.GetAvailableTimeOfFacilityResponse_Interval_BUSY, requestID, 0)
+			case busyBlockID != 0:
+				appendInterval(start, end, facility.GetAvailableTimeOfFacilityResponse_Interval_BUSY, 0, busyBlockID)
+			default:
+				appendInterval(start, end, facility.GetAvailableTimeOfFacilityResponse_Interval_FREE, 0, 0)
 			}
 		}
 
+		appendInterval(closing, nextDay, facility.GetAvailableTimeOfFacilityResponse_Interval_CLOSED, 0, 0)
 	}
 
-	return &facility.GetAvailableTimeOfFacilityResponse{Day: resultArray}
+	return result
 }
 
-// getFacilityInfoWithRequests is function to preapare facility info for GetAvailableTimeOfFacility API
-func getFacilityInfoWithRequests(fs *FacilityServer, facilityID int64, start *timestamp.Timestamp, end *timestamp.Timestamp) (*FacilityInfoWithRequest, typing.CustomError) {
-	errorChannel := make(chan typing.CustomError, 2)
-	faicilityInfoChannel := make(chan *common.Facility)
-	faiclityRequestsChannel := make(chan []*common.FacilityRequest)
-
-	go func() {
-		facilityInfo, err := fs.dbs.GetFacilityInfo(facilityID)
-		if err != nil {
-			errorChannel <- err
+// findBlockingRequest is a function to get ID of the request covering the instant, 0 means none
+func findBlockingRequest(facilityRequests []*common.FacilityRequest, instant time.Time) int64 {
+	for _, request := range facilityRequests {
+		if !request.Start.AsTime().After(instant) && request.Finish.AsTime().After(instant) {
+			return request.Id
 		}
-		faicilityInfoChannel <- facilityInfo
-	}()
-	go func() {
-		facilityRequests, err := fs.dbs.GetApprovedFacilityRequestList(facilityID, start, end)
-		if err != nil {
-			errorChannel <- err
+	}
+	return 0
+}
+
+// findBlockingBusyBlock is a function to get ID of the busy block covering the instant, 0 means none
+func findBlockingBusyBlock(busyBlocks []*common.BusyBlock, instant time.Time) int64 {
+	for _, block := range busyBlocks {
+		if !block.Start.AsTime().After(instant) && block.Finish.AsTime().After(instant) {
+			return block.Id
 		}
-		faiclityRequestsChannel <- facilityRequests
-	}()
+	}
+	return 0
+}
 
-	facilityInfo := <-faicilityInfoChannel
-	facilityRequests := <-faiclityRequestsChannel
+// getFacilityInfoWithRequests is function to preapare facility info for GetAvailableTimeOfFacility API, start and end are converted to the facility's time zone
+func getFacilityInfoWithRequests(ctx context.Context, fs *FacilityServer, facilityID int64, start *timestamp.Timestamp, end *timestamp.Timestamp) (*FacilityInfoWithRequest, typing.CustomError) {
+	facilityInfo, err := fs.dbs.GetFacilityInfo(ctx, facilityID)
+	if err != nil {
+		return nil, err
+	}
 
-	close(errorChannel)
-	for err := range errorChannel {
+	location, locationErr := time.LoadLocation(facilityInfo.TimeZone)
+	if locationErr != nil {
+		return nil, &typing.DatabaseError{StatusCode: codes.DataLoss, Err: locationErr}
+	}
+	startTime, _ := ptypes.Timestamp(start)
+	finishTime, _ := ptypes.Timestamp(end)
+	startTime = startTime.In(location)
+	finishTime = finishTime.In(location)
+
+	window, err := fs.dbs.GetBookingWindow(ctx, facilityInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	err = isAbleToGetAvailableTimeOfFacility(startTime, finishTime, window)
+	if err != nil {
+		return nil, err
+	}
+
+	facilityRequests, err := fs.dbs.GetApprovedFacilityRequestList(ctx, facilityID, startTime, finishTime)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := fs.dbs.GetOperatingHourOverrides(ctx, facilityID, startTime, finishTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// busy blocks are fetched for whole days like approved requests
+	windowStart := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), 0, 0, 0, 0, location)
+	windowFinish := time.Date(finishTime.Year(), finishTime.Month(), finishTime.Day()+1, 0, 0, 0, 0, location)
+	busyBlocks, err := fs.dbs.GetBusyBlocks(ctx, facilityID, windowStart, windowFinish)
+	if err != nil {
 		return nil, err
 	}
-	close(faicilityInfoChannel)
-	close(faiclityRequestsChannel)
 
-	return &FacilityInfoWithRequest{Info: facilityInfo, Requests: facilityRequests}, nil
+	return &FacilityInfoWithRequest{Info: facilityInfo, Requests: facilityRequests, BusyBlocks: busyBlocks, Overrides: overrides, Start: startTime, Finish: finishTime}, nil
 }
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	common "onepass.app/facility/hts/common"
+	facility "onepass.app/facility/hts/facility"
+	cache "onepass.app/facility/internal/cache"
+	model "onepass.app/facility/internal/model"
+	typing "onepass.app/facility/internal/typing"
 )
 
 func TestSomething1(t *testing.T) {
@@ -19,3 +29,328 @@ func TestSomething1(t *testing.T) {
 	assert.Empty(t, a, "A is empty")
 	// log.Println(a)
 }
+
+func TestGenerateFacilityAvailabilityResultSlot(t *testing.T) {
+	assert := assert.New(t)
+
+	startTime := time.Date(2021, time.February, 22, 0, 0, 0, 0, time.UTC)
+	operatingHours := []*common.OperatingHour{
+		{Day: common.DayOfWeek_MON, StartHour: 9, FinishHour: 12},
+	}
+
+	resultArray := createResultEmptyArray(startTime, startTime.AddDate(0, 0, 1), operatingHours, nil, 30)
+	assert.Equal(2, len(resultArray))
+	assert.Equal(6, len(resultArray[0].Items))
+	assert.Nil(resultArray[1].Items)
+
+	requests := []*common.FacilityRequest{{
+		Start:  timestamppb.New(time.Date(2021, time.February, 22, 9, 30, 0, 0, time.UTC)),
+		Finish: timestamppb.New(time.Date(2021, time.February, 22, 10, 30, 0, 0, time.UTC)),
+	}}
+	result := generateFacilityAvailabilityResult(resultArray, startTime, operatingHours, nil, requests, nil, 30)
+	assert.Equal(int64(30), result.SlotMinutes)
+	assert.Equal([]bool{true, false, false, true, true, true}, result.Day[0].Items)
+}
+
+func TestGenerateFacilityAvailabilityResultMultiDay(t *testing.T) {
+	assert := assert.New(t)
+
+	startTime := time.Date(2021, time.February, 27, 0, 0, 0, 0, time.UTC)
+	operatingHours := make([]*common.OperatingHour, 7)
+	for i := range operatingHours {
+		operatingHours[i] = &common.OperatingHour{Day: common.DayOfWeek(i), StartHour: 9, FinishHour: 12}
+	}
+
+	resultArray := createResultEmptyArray(startTime, startTime.AddDate(0, 0, 3), operatingHours, nil, 60)
+	requests := []*common.FacilityRequest{{
+		Start:  timestamppb.New(time.Date(2021, time.February, 28, 10, 0, 0, 0, time.UTC)),
+		Finish: timestamppb.New(time.Date(2021, time.March, 2, 11, 0, 0, 0, time.UTC)),
+	}}
+	result := generateFacilityAvailabilityResult(resultArray, startTime, operatingHours, nil, requests, nil, 60)
+	assert.Equal(4, len(result.Day))
+	assert.Equal([]bool{true, true, true}, result.Day[0].Items)
+	assert.Equal([]bool{true, false, false}, result.Day[1].Items)
+	assert.Equal([]bool{false, false, false}, result.Day[2].Items)
+	assert.Equal([]bool{false, false, true}, result.Day[3].Items)
+}
+
+func TestGenerateFacilityAvailabilityResultTimeZone(t *testing.T) {
+	assert := assert.New(t)
+
+	location, _ := time.LoadLocation("Asia/Bangkok")
+	startTime := time.Date(2021, time.February, 22, 0, 0, 0, 0, location)
+	operatingHours := []*common.OperatingHour{
+		{Day: common.DayOfWeek_MON, StartHour: 9, FinishHour: 12},
+	}
+
+	resultArray := createResultEmptyArray(startTime, startTime, operatingHours, nil, 60)
+	requests := []*common.FacilityRequest{{
+		Start:  timestamppb.New(time.Date(2021, time.February, 22, 3, 0, 0, 0, time.UTC)),
+		Finish: timestamppb.New(time.Date(2021, time.February, 22, 4, 0, 0, 0, time.UTC)),
+	}}
+	result := generateFacilityAvailabilityResult(resultArray, startTime, operatingHours, nil, requests, nil, 60)
+	assert.Equal([]bool{true, false, true}, result.Day[0].Items)
+}
+
+func TestGenerateFacilityAvailabilityResultDaylightSaving(t *testing.T) {
+	assert := assert.New(t)
+
+	location, _ := time.LoadLocation("America/New_York")
+	// clocks go forward at 2:00 on this Sunday so 9:00 is 8 hours after midnight
+	startTime := time.Date(2021, time.March, 14, 0, 0, 0, 0, location)
+	operatingHours := []*common.OperatingHour{{Day: common.DayOfWeek_SUN, StartHour: 9, FinishHour: 12}}
+
+	resultArray := createResultEmptyArray(startTime, startTime, operatingHours, nil, 60)
+	requests := []*common.FacilityRequest{{
+		Start:  timestamppb.New(time.Date(2021, time.March, 14, 9, 0, 0, 0, location)),
+		Finish: timestamppb.New(time.Date(2021, time.March, 14, 10, 0, 0, 0, location)),
+	}}
+	result := generateFacilityAvailabilityResult(resultArray, startTime, operatingHours, nil, requests, nil, 60)
+	assert.Equal([]bool{false, true, true}, result.Day[0].Items)
+}
+
+func TestGenerateFacilityAvailabilityResultOverride(t *testing.T) {
+	assert := assert.New(t)
+
+	startTime := time.Date(2021, time.February, 22, 0, 0, 0, 0, time.UTC)
+	operatingHours := []*common.OperatingHour{
+		{Day: common.DayOfWeek_MON, StartHour: 9, FinishHour: 12},
+		{Day: common.DayOfWeek_TUE, StartHour: 9, FinishHour: 12},
+	}
+	overrides := map[string]*common.OperatingHourOverride{
+		"2021-02-22": {Date: "2021-02-22", IsClosed: true},
+		"2021-02-23": {Date: "2021-02-23", StartHour: 10, FinishHour: 11},
+	}
+
+	resultArray := createResultEmptyArray(startTime, startTime.AddDate(0, 0, 1), operatingHours, overrides, 60)
+	assert.Nil(resultArray[0].Items)
+	assert.Equal(1, len(resultArray[1].Items))
+
+	result := generateFacilityAvailabilityResult(resultArray, startTime, operatingHours, overrides, nil, nil, 60)
+	assert.Equal([]bool{true}, result.Day[1].Items)
+}
+
+func TestExpandFacilityRequestRecurrence(t *testing.T) {
+	assert := assert.New(t)
+
+	location, _ := time.LoadLocation("Asia/Bangkok")
+	start := time.Date(2021, time.March, 1, 18, 0, 0, 0, location)
+	in := &facility.CreateRecurringFacilityRequestRequest{
+		EventId:    2,
+		FacilityId: 3,
+		Start:      timestamppb.New(start),
+		End:        timestamppb.New(start.Add(2 * time.Hour)),
+		Rrule:      "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+	}
+	rule, occurrences, err := expandFacilityRequestRecurrence(in, location)
+	assert.Nil(err)
+	assert.Contains(rule, "FREQ=WEEKLY")
+	assert.Equal(4, len(occurrences))
+	expectedDays := []int{1, 3, 8, 10}
+	for i, occurrence := range occurrences {
+		occurrenceStart := occurrence.Start.AsTime().In(location)
+		assert.Equal(expectedDays[i], occurrenceStart.Day())
+		assert.Equal(18, occurrenceStart.Hour())
+		assert.Equal(2*time.Hour, occurrence.Finish.AsTime().Sub(occurrence.Start.AsTime()))
+		assert.Equal(int64(3), occurrence.FacilityId)
+	}
+
+	in.Rrule = ""
+	in.Frequency = facility.Frequency_DAILY
+	in.Until = timestamppb.New(start.AddDate(0, 0, 2))
+	_, occurrences, err = expandFacilityRequestRecurrence(in, location)
+	assert.Nil(err)
+	assert.Equal(3, len(occurrences))
+
+	in.Until = nil
+	_, _, err = expandFacilityRequestRecurrence(in, location)
+	assert.NotNil(err)
+
+	in.Rrule = "FREQ=DAILY"
+	_, _, err = expandFacilityRequestRecurrence(in, location)
+	assert.NotNil(err)
+
+	in.Rrule = "FREQ=DAILY;COUNT=101"
+	_, _, err = expandFacilityRequestRecurrence(in, location)
+	assert.NotNil(err)
+
+	in.Rrule = "FREQ=SOMETIMES;COUNT=2"
+	_, _, err = expandFacilityRequestRecurrence(in, location)
+	assert.NotNil(err)
+}
+
+func TestCheckCapacity(t *testing.T) {
+	assert := assert.New(t)
+
+	hall := &common.Facility{Capacity: 50}
+	assert.Nil(checkCapacity(hall, &common.Event{ExpectedAttendance: 50}, false))
+	assert.Nil(checkCapacity(hall, &common.Event{}, false))
+	assert.Nil(checkCapacity(&common.Facility{}, &common.Event{ExpectedAttendance: 500}, false))
+	assert.Nil(checkCapacity(hall, &common.Event{ExpectedAttendance: 500}, true))
+
+	err := checkCapacity(hall, &common.Event{ExpectedAttendance: 500}, false)
+	assert.NotNil(err)
+	assert.Equal(codes.FailedPrecondition, err.Code())
+}
+
+func TestIsAbleToGetAvailableTimeOfFacility(t *testing.T) {
+	assert := assert.New(t)
+
+	today := time.Now().UTC()
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	assert.Nil(isAbleToGetAvailableTimeOfFacility(today, today.AddDate(0, 0, 20), nil))
+	assert.NotNil(isAbleToGetAvailableTimeOfFacility(today, today.AddDate(0, 0, 20), &common.BookingWindow{MaxAdvanceDays: 14}))
+	assert.Nil(isAbleToGetAvailableTimeOfFacility(today, today.AddDate(0, 0, 40), &common.BookingWindow{MaxAdvanceDays: 60}))
+	assert.NotNil(isAbleToGetAvailableTimeOfFacility(today.AddDate(0, 0, -1), today, nil))
+}
+
+func TestQuota(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(int64(30), remainingQuota(600, 570))
+	assert.Equal(int64(0), remainingQuota(600, 660))
+
+	err := &typing.QuotaError{Name: "pending requests", Limit: 3, Usage: 4}
+	assert.Equal(codes.ResourceExhausted, err.Code())
+	assert.Equal("quota error: pending requests would be 4 exceeding 3", err.Error())
+}
+
+func TestGenerateFacilityFreeBusyResult(t *testing.T) {
+	assert := assert.New(t)
+
+	at := func(month time.Month, day int, hour int, minute int) time.Time {
+		return time.Date(2021, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	operatingHours := make([]*common.OperatingHour, 7)
+	for i := range operatingHours {
+		operatingHours[i] = &common.OperatingHour{Day: common.DayOfWeek(i), StartHour: 9, FinishHour: 12}
+	}
+	requests := []*common.FacilityRequest{{
+		Id:     7,
+		Start:  timestamppb.New(at(time.February, 28, 10, 0)),
+		Finish: timestamppb.New(at(time.March, 1, 11, 0)),
+	}}
+
+	startTime := at(time.February, 28, 0, 0)
+	now := at(time.February, 28, 9, 30)
+	result := generateFacilityFreeBusyResult(startTime, at(time.March, 1, 0, 0), now, operatingHours, nil, requests, nil)
+
+	expected := []struct {
+		start     time.Time
+		end       time.Time
+		status    facility.GetAvailableTimeOfFacilityResponse_Interval_Status
+		requestID int64
+	}{
+		{at(time.February, 28, 0, 0), at(time.February, 28, 9, 0), facility.GetAvailableTimeOfFacilityResponse_Interval_CLOSED, 0},
+		{at(time.February, 28, 9, 0), at(time.February, 28, 9, 30), facility.GetAvailableTimeOfFacilityResponse_Interval_PAST, 0},
+		{at(time.February, 28, 9, 30), at(time.February, 28, 10, 0), facility.GetAvailableTimeOfFacilityResponse_Interval_FREE, 0},
+		{at(time.February, 28, 10, 0), at(time.February, 28, 12, 0), facility.GetAvailableTimeOfFacilityResponse_Interval_BUSY, 7},
+		{at(time.February, 28, 12, 0), at(time.March, 1, 9, 0), facility.GetAvailableTimeOfFacilityResponse_Interval_CLOSED, 0},
+		{at(time.March, 1, 9, 0), at(time.March, 1, 11, 0), facility.GetAvailableTimeOfFacilityResponse_Interval_BUSY, 7},
+		{at(time.March, 1, 11, 0), at(time.March, 1, 12, 0), facility.GetAvailableTimeOfFacilityResponse_Interval_FREE, 0},
+		{at(time.March, 1, 12, 0), at(time.March, 2, 0, 0), facility.GetAvailableTimeOfFacilityResponse_Interval_CLOSED, 0},
+	}
+	assert.Equal(len(expected), len(result))
+	for i, interval := range result {
+		assert.Equal(expected[i].start, interval.Start.AsTime(), i)
+		assert.Equal(expected[i].end, interval.End.AsTime(), i)
+		assert.Equal(expected[i].status, interval.Status, i)
+		assert.Equal(expected[i].requestID, interval.RequestId, i)
+	}
+
+	overrides := map[string]*common.OperatingHourOverride{"2021-03-01": {Date: "2021-03-01", IsClosed: true}}
+	result = generateFacilityFreeBusyResult(at(time.March, 1, 0, 0), at(time.March, 1, 0, 0), now, operatingHours, overrides, nil, nil)
+	assert.Equal(1, len(result))
+	assert.Equal(facility.GetAvailableTimeOfFacilityResponse_Interval_CLOSED, result[0].Status)
+}
+
+func TestGenerateFacilityFreeBusyResultBusyBlock(t *testing.T) {
+	assert := assert.New(t)
+
+	at := func(hour int, minute int) time.Time {
+		return time.Date(2021, time.March, 1, hour, minute, 0, 0, time.UTC)
+	}
+	operatingHours := []*common.OperatingHour{{Day: common.DayOfWeek_MON, StartHour: 9, FinishHour: 12}}
+	requests := []*common.FacilityRequest{{Id: 7, Start: timestamppb.New(at(9, 0)), Finish: timestamppb.New(at(10, 0))}}
+	busyBlocks := []*common.BusyBlock{{Id: 3, Start: timestamppb.New(at(10, 0)), Finish: timestamppb.New(at(11, 30))}}
+
+	result := generateFacilityFreeBusyResult(at(0, 0), at(0, 0), at(0, 0), operatingHours, nil, requests, busyBlocks)
+	assert.Equal(5, len(result))
+	assert.Equal(int64(7), result[1].RequestId)
+	assert.Equal(facility.GetAvailableTimeOfFacilityResponse_Interval_BUSY, result[2].Status)
+	assert.Equal(int64(0), result[2].RequestId)
+	assert.Equal(int64(3), result[2].BusyBlockId)
+	assert.Equal(at(11, 30), result[2].End.AsTime())
+	assert.Equal(facility.GetAvailableTimeOfFacilityResponse_Interval_FREE, result[3].Status)
+
+	resultArray := createResultEmptyArray(at(0, 0), at(0, 0), operatingHours, nil, 30)
+	grid := generateFacilityAvailabilityResult(resultArray, at(0, 0), operatingHours, nil, requests, busyBlocks, 30)
+	assert.Equal([]bool{false, false, false, false, false, true}, grid.Day[0].Items)
+}
+
+func TestGenerateFacilityFreeBusyResultDaylightSaving(t *testing.T) {
+	assert := assert.New(t)
+
+	location, _ := time.LoadLocation("America/New_York")
+	// clocks go forward at 2:00 on this Sunday so the day has 23 hours
+	at := func(hour int) time.Time {
+		return time.Date(2021, time.March, 14, hour, 0, 0, 0, location)
+	}
+	operatingHours := []*common.OperatingHour{{Day: common.DayOfWeek_SUN, StartHour: 9, FinishHour: 12}}
+
+	result := generateFacilityFreeBusyResult(at(0), at(0), at(0), operatingHours, nil, nil, nil)
+	assert.Equal(3, len(result))
+	assert.True(at(9).Equal(result[1].Start.AsTime()))
+	assert.True(at(12).Equal(result[1].End.AsTime()))
+	assert.Equal(facility.GetAvailableTimeOfFacilityResponse_Interval_FREE, result[1].Status)
+}
+
+func TestIsFacilityDecider(t *testing.T) {
+	assert := assert.New(t)
+
+	// user 42 owns the facility and user 5 approves for its chain, other lookups of the account service would panic
+	permissions := cache.NewPermissionCache(time.Minute, 10)
+	key := cache.PermissionKey{UserID: 42, OrganizationID: 1, Permission: common.Permission_UPDATE_FACILITY}
+	permissions.Get(context.Background(), key, func() (bool, typing.CustomError) { return true, nil })
+	fs := &FacilityServer{permissions: permissions}
+	chain := &common.ApprovalChain{Stages: []*common.ApprovalStage{{Name: "Dean", ApproverIds: []int64{5}}}}
+
+	isDecider, err := isFacilityDecider(context.Background(), fs, 42, &common.Facility{OrganizationId: 1})
+	assert.Nil(err)
+	assert.True(isDecider)
+
+	isDecider, err = isFacilityDecider(context.Background(), fs, 42, &common.Facility{OrganizationId: 1, ApprovalChain: chain})
+	assert.False(isDecider)
+	assert.Equal(codes.PermissionDenied, err.Code())
+
+	isDecider, err = isFacilityDecider(context.Background(), fs, 5, &common.Facility{OrganizationId: 1, ApprovalChain: chain})
+	assert.Nil(err)
+	assert.True(isDecider)
+}
+
+func TestIsMatchingFacilityRequestChange(t *testing.T) {
+	assert := assert.New(t)
+
+	change := &model.FacilityRequestChange{ID: 1, EventID: 2, FacilityID: 3, OrganizationID: 4}
+	assert.True(isMatchingFacilityRequestChange(&facility.WatchFacilityRequestsRequest{EventId: 2}, change))
+	assert.True(isMatchingFacilityRequestChange(&facility.WatchFacilityRequestsRequest{OrganizationId: 4, FacilityId: 3}, change))
+	assert.False(isMatchingFacilityRequestChange(&facility.WatchFacilityRequestsRequest{EventId: 2, FacilityId: 5}, change))
+	assert.False(isMatchingFacilityRequestChange(&facility.WatchFacilityRequestsRequest{OrganizationId: 6}, change))
+}
+
+func TestNewServiceError(t *testing.T) {
+	assert := assert.New(t)
+
+	err := newServiceError("Account service", status.Error(codes.DeadlineExceeded, "context deadline exceeded"))
+	assert.Equal(codes.DeadlineExceeded, err.Code())
+	assert.Equal("context error: Account service call is timed out", err.Error())
+
+	err = newServiceError("Account service", status.Error(codes.Canceled, "context canceled"))
+	assert.Equal(codes.Canceled, err.Code())
+
+	err = newServiceError("Account service", status.Error(codes.Unavailable, "connection refused"))
+	assert.Equal(codes.Unavailable, err.Code())
+
+	databaseErr := &typing.DatabaseError{Err: fmt.Errorf("query: %w", context.DeadlineExceeded), StatusCode: codes.Internal}
+	assert.Equal(codes.DeadlineExceeded, databaseErr.Code())
+}
@@ -6,19 +6,23 @@ import (
 	"log"
 	"net"
 	"os"
+	"sort"
+	"strings"
 	"time"
-
-	"github.com/golang/protobuf/ptypes"
-	empty "github.com/golang/protobuf/ptypes/empty"
+	_ "time/tzdata"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 	account "onepass.app/facility/hts/account"
 	"onepass.app/facility/hts/common"
 	facility "onepass.app/facility/hts/facility"
 	organizer "onepass.app/facility/hts/organizer"
 	participant "onepass.app/facility/hts/participant"
+	auth "onepass.app/facility/internal/auth"
+	cache "onepass.app/facility/internal/cache"
 	database "onepass.app/facility/internal/database"
 	"onepass.app/facility/internal/helper"
 	typing "onepass.app/facility/internal/typing"
@@ -33,37 +37,70 @@ type FacilityServer struct {
 	participant participant.ParticipantServiceClient
 	organizer   organizer.OrganizationServiceClient
 	dbs         *database.DataService
+	permissions *cache.PermissionCache
+	timeouts    ServiceTimeouts
 }
 
-// GetFacilityList is a function to list all facilities owned by organization
+// defaultServiceTimeout is deadline of a call to other service when its HTS_SVC_*_TIMEOUT is not set
+const defaultServiceTimeout = 3 * time.Second
+
+// GetFacilityList is a function to list a page of facilities owned by organization
 func (fs *FacilityServer) GetFacilityList(ctx context.Context, in *facility.GetFacilityListRequest) (*facility.GetFacilityListResponse, error) {
-	list, err := fs.dbs.GetFacilityList(in.OrganizationId)
+	list, nextPageToken, err := fs.dbs.GetFacilityList(ctx, in.OrganizationId, in.PageSize, in.PageToken)
 
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
 
 	return &facility.GetFacilityListResponse{
-		Facilities: list,
+		Facilities:    list,
+		NextPageToken: nextPageToken,
 	}, nil
 }
 
-// GetAvailableFacilityList is a function to list all available facilities
-func (fs *FacilityServer) GetAvailableFacilityList(ctx context.Context, in *empty.Empty) (*facility.GetAvailableFacilityListResponse, error) {
-	list, err := fs.dbs.GetAvailableFacilityList()
+// GetAvailableFacilityList is a function to list a page of available facilities
+func (fs *FacilityServer) GetAvailableFacilityList(ctx context.Context, in *facility.GetAvailableFacilityListRequest) (*facility.GetAvailableFacilityListResponse, error) {
+	list, nextPageToken, err := fs.dbs.GetAvailableFacilityList(ctx, in.PageSize, in.PageToken)
 
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
 
 	return &facility.GetAvailableFacilityListResponse{
+		Facilities:    list,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// SearchNearbyFacilities is a function to list facilities within radius of a point ordered by distance
+func (fs *FacilityServer) SearchNearbyFacilities(ctx context.Context, in *facility.SearchNearbyFacilitiesRequest) (*facility.SearchNearbyFacilitiesResponse, error) {
+	list, err := fs.dbs.SearchNearbyFacilities(ctx, in.Latitude, in.Longitude, in.RadiusMeters, in.Start, in.End, in.Limit)
+
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	return &facility.SearchNearbyFacilitiesResponse{
+		Facilities: list,
+	}, nil
+}
+
+// FindAvailableFacilities is a function to find facilities that are open and free for the whole time window
+func (fs *FacilityServer) FindAvailableFacilities(ctx context.Context, in *facility.FindAvailableFacilitiesRequest) (*facility.FindAvailableFacilitiesResponse, error) {
+	list, err := fs.dbs.FindAvailableFacilities(ctx, in)
+
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	return &facility.FindAvailableFacilitiesResponse{
 		Facilities: list,
 	}, nil
 }
 
 // GetFacilityInfo is a function to get facility’s information
 func (fs *FacilityServer) GetFacilityInfo(ctx context.Context, in *facility.GetFacilityInfoRequest) (*common.Facility, error) {
-	result, err := fs.dbs.GetFacilityInfo(in.FacilityId)
+	result, err := fs.dbs.GetFacilityInfo(ctx, in.FacilityId)
 
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
@@ -72,20 +109,35 @@ func (fs *FacilityServer) GetFacilityInfo(ctx context.Context, in *facility.GetF
 	return result, nil
 }
 
-// ApproveFacilityRequest is a function to reject facility’s request by id
+// ApproveFacilityRequest is a function to approve facility’s request by id
 func (fs *FacilityServer) ApproveFacilityRequest(ctx context.Context, in *facility.ApproveFacilityRequestRequest) (*common.Result, error) {
-	isConditionPassed, err := isAbleToApproveFacilityRequest(fs, in)
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	isConditionPassed, err := isAbleToApproveFacilityRequest(ctx, fs, userID, in)
 
 	if !isConditionPassed || err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
 
-	err = fs.dbs.ApproveFacilityRequest(in.RequestId)
+	isApproved, rejectedIDs, err := fs.dbs.ApproveFacilityRequest(ctx, in.RequestId, userID)
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
 
+	if !isApproved {
+		return &common.Result{
+			IsOk:        true,
+			Description: fmt.Sprintf("Request ID: %d approval has been recorded, waiting for other stages", in.RequestId),
+		}, nil
+	}
+
 	description := fmt.Sprintf("Request ID: %d has been aproved", in.RequestId)
+	if len(rejectedIDs) != 0 {
+		description += fmt.Sprintf(", overlapping request ID: %v has been rejected", rejectedIDs)
+	}
 	return &common.Result{
 		IsOk:        true,
 		Description: description,
@@ -94,12 +146,17 @@ func (fs *FacilityServer) ApproveFacilityRequest(ctx context.Context, in *facili
 
 // RejectFacilityRequest is a function to reject facility’s request by id
 func (fs *FacilityServer) RejectFacilityRequest(ctx context.Context, in *facility.RejectFacilityRequestRequest) (*common.Result, error) {
-	isConditionPassed, err := isAbleToRejectFacilityRequest(fs, in)
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	isConditionPassed, err := isAbleToRejectFacilityRequest(ctx, fs, userID, in)
 	if !isConditionPassed || err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
 
-	err = fs.dbs.RejectFacilityRequest(in.RequestId, in.Reason)
+	err = fs.dbs.RejectFacilityRequest(ctx, in.RequestId, userID, in.Reason)
 
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
@@ -112,15 +169,44 @@ func (fs *FacilityServer) RejectFacilityRequest(ctx context.Context, in *facilit
 	}, nil
 }
 
+// CancelFacilityRequest is a function to cancel facility’s request by id
+func (fs *FacilityServer) CancelFacilityRequest(ctx context.Context, in *facility.CancelFacilityRequestRequest) (*common.Result, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	isConditionPassed, err := isAbleToCancelFacilityRequest(ctx, fs, userID, in)
+	if !isConditionPassed || err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	err = fs.dbs.CancelFacilityRequest(ctx, in.RequestId, userID)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	description := fmt.Sprintf("Request ID: %d has been cancelled", in.RequestId)
+	return &common.Result{
+		IsOk:        true,
+		Description: description,
+	}, nil
+}
+
 // CreateFacilityRequest is a function to create facility’s request by id
 func (fs *FacilityServer) CreateFacilityRequest(ctx context.Context, in *facility.CreateFacilityRequestRequest) (*common.FacilityRequest, error) {
-	isConditionPassed, err := isAbleToCreateFacilityRequest(fs, in)
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
 
-	if !isConditionPassed || err != nil {
+	event, requestStatus, err := isAbleToCreateFacilityRequest(ctx, fs, userID, in)
+
+	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
 
-	result, err := fs.dbs.CreateFacilityRequest(in.EventId, in.FacilityId, in.Start, in.End)
+	result, err := fs.dbs.CreateFacilityRequest(ctx, in.EventId, event.OrganizationId, in.FacilityId, in.Start, in.End, requestStatus)
 
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
@@ -129,10 +215,168 @@ func (fs *FacilityServer) CreateFacilityRequest(ctx context.Context, in *facilit
 	return result, nil
 }
 
+// CreateRecurringFacilityRequest is a function to create facility’s requests from recurrence rule, occurrences failed validation are listed in the response
+func (fs *FacilityServer) CreateRecurringFacilityRequest(ctx context.Context, in *facility.CreateRecurringFacilityRequestRequest) (*facility.CreateRecurringFacilityRequestResponse, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	event, err := getEvent(ctx, fs.participant, fs.timeouts.Participant, in.EventId)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	isConditionPassed, err := isEventOrganizer(ctx, fs, userID, event)
+	if !isConditionPassed || err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	facilityInfo, err := fs.dbs.GetFacilityInfo(ctx, in.FacilityId)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	err = checkCapacity(facilityInfo, event, in.AllowOverCapacity)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	location, locationErr := time.LoadLocation(facilityInfo.TimeZone)
+	if locationErr != nil {
+		return nil, status.Error(codes.DataLoss, locationErr.Error())
+	}
+
+	rule, occurrences, err := expandFacilityRequestRecurrence(in, location)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	var validOccurrences []*common.FacilityRequest
+	var failures []*facility.CreateRecurringFacilityRequestResponse_FailedOccurrence
+	for _, occurrence := range occurrences {
+		isTimeOverlap, err := fs.dbs.IsOverlapTime(ctx, in.FacilityId, occurrence.Start, occurrence.Finish, true)
+		if err == nil && isTimeOverlap {
+			err = &typing.AlreadyExistError{Name: "Facility is booked at that time"}
+		}
+		if err == nil {
+			err = fs.dbs.CheckQuota(ctx, facilityInfo, event.OrganizationId, occurrence.Start.AsTime(), occurrence.Finish.AsTime(), validOccurrences)
+		}
+		if err != nil {
+			failures = append(failures, &facility.CreateRecurringFacilityRequestResponse_FailedOccurrence{
+				Start:  occurrence.Start,
+				Finish: occurrence.Finish,
+				Reason: err.Error(),
+			})
+			continue
+		}
+		validOccurrences = append(validOccurrences, occurrence)
+	}
+
+	if len(validOccurrences) == 0 {
+		return &facility.CreateRecurringFacilityRequestResponse{Failures: failures}, nil
+	}
+
+	seriesID, result, err := fs.dbs.CreateFacilityRequestSeries(ctx, in.EventId, event.OrganizationId, in.FacilityId, rule, validOccurrences)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	return &facility.CreateRecurringFacilityRequestResponse{
+		SeriesId: seriesID,
+		Requests: result,
+		Failures: failures,
+	}, nil
+}
+
+// ApproveFacilityRequestSeries is a function to approve pending facility’s requests of the series
+func (fs *FacilityServer) ApproveFacilityRequestSeries(ctx context.Context, in *facility.ApproveFacilityRequestSeriesRequest) (*common.Result, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	facilityRequests, err := isAbleToUpdateFacilityRequestSeries(ctx, fs, userID, in.SeriesId)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	var approvedIDs []int64
+	var waitingIDs []int64
+	var failures []string
+	for _, facilityRequest := range facilityRequests {
+		if facilityRequest.Status != common.Status_PENDING {
+			continue
+		}
+		isApproved, _, err := fs.dbs.ApproveFacilityRequest(ctx, facilityRequest.Id, userID)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("request ID: %d %s", facilityRequest.Id, err.Error()))
+			continue
+		}
+		if !isApproved {
+			waitingIDs = append(waitingIDs, facilityRequest.Id)
+			continue
+		}
+		approvedIDs = append(approvedIDs, facilityRequest.Id)
+	}
+
+	description := fmt.Sprintf("Series ID: %d request ID: %v has been aproved", in.SeriesId, approvedIDs)
+	if len(waitingIDs) != 0 {
+		description += fmt.Sprintf(", request ID: %v waiting for other stages", waitingIDs)
+	}
+	if len(failures) != 0 {
+		description += ", failed: " + strings.Join(failures, ", ")
+	}
+	return &common.Result{
+		IsOk:        len(failures) == 0,
+		Description: description,
+	}, nil
+}
+
+// RejectFacilityRequestSeries is a function to reject pending facility’s requests of the series
+func (fs *FacilityServer) RejectFacilityRequestSeries(ctx context.Context, in *facility.RejectFacilityRequestSeriesRequest) (*common.Result, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	facilityRequests, err := isAbleToUpdateFacilityRequestSeries(ctx, fs, userID, in.SeriesId)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	var rejectedIDs []int64
+	var failures []string
+	for _, facilityRequest := range facilityRequests {
+		if facilityRequest.Status != common.Status_PENDING {
+			continue
+		}
+		if err := fs.dbs.RejectFacilityRequest(ctx, facilityRequest.Id, userID, in.Reason); err != nil {
+			failures = append(failures, fmt.Sprintf("request ID: %d %s", facilityRequest.Id, err.Error()))
+			continue
+		}
+		rejectedIDs = append(rejectedIDs, facilityRequest.Id)
+	}
+
+	description := fmt.Sprintf("Series ID: %d request ID: %v has been rejected", in.SeriesId, rejectedIDs)
+	if len(failures) != 0 {
+		description += ", failed: " + strings.Join(failures, ", ")
+	}
+	return &common.Result{
+		IsOk:        len(failures) == 0,
+		Description: description,
+	}, nil
+}
+
 // GetFacilityRequestList is a function to get facility request’s of the organization
 func (fs *FacilityServer) GetFacilityRequestList(ctx context.Context, in *facility.GetFacilityRequestListRequest) (*facility.GetFacilityRequestListResponse, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
 	permission := common.Permission_UPDATE_FACILITY
-	isPermission, err := hasPermission(fs.account, in.UserId, in.OrganizationId, permission)
+	isPermission, err := hasPermission(ctx, fs, userID, in.OrganizationId, permission)
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
@@ -141,25 +385,31 @@ func (fs *FacilityServer) GetFacilityRequestList(ctx context.Context, in *facili
 		return nil, status.Error(codes.PermissionDenied, (&typing.PermissionError{Type: permission}).Error())
 	}
 
-	result, err := fs.dbs.GetFacilityRequestList(in.OrganizationId)
+	result, nextPageToken, err := fs.dbs.GetFacilityRequestList(ctx, in.OrganizationId, in.Option)
 
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
 
 	return &facility.GetFacilityRequestListResponse{
-		Requests: result,
+		Requests:      result,
+		NextPageToken: nextPageToken,
 	}, nil
 }
 
 // GetFacilityRequestsListStatus is a function to get facility’s of the event
 func (fs *FacilityServer) GetFacilityRequestsListStatus(ctx context.Context, in *facility.GetFacilityRequestsListStatusRequest) (*facility.GetFacilityRequestsListStatusResponse, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
 	permission := common.Permission_UPDATE_FACILITY
-	event, err := getEvent(fs.participant, in.EventId)
+	event, err := getEvent(ctx, fs.participant, fs.timeouts.Participant, in.EventId)
 	if err != nil {
-		return nil, err
+		return nil, status.Error(err.Code(), err.Error())
 	}
-	isPermission, err := hasPermission(fs.account, in.UserId, event.OrganizationId, permission)
+	isPermission, err := hasPermission(ctx, fs, userID, event.OrganizationId, permission)
 
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
@@ -168,25 +418,31 @@ func (fs *FacilityServer) GetFacilityRequestsListStatus(ctx context.Context, in
 		return nil, status.Error(codes.PermissionDenied, (&typing.PermissionError{Type: permission}).Error())
 	}
 
-	result, err := fs.dbs.GetFacilityRequestsListStatus(in.EventId)
+	result, nextPageToken, err := fs.dbs.GetFacilityRequestsListStatus(ctx, in.EventId, in.Option)
 
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
 
 	return &facility.GetFacilityRequestsListStatusResponse{
-		Requests: result,
+		Requests:      result,
+		NextPageToken: nextPageToken,
 	}, nil
 }
 
 // GetFacilityRequestStatus is a function to get facility request’s of the event
 func (fs *FacilityServer) GetFacilityRequestStatus(ctx context.Context, in *facility.GetFacilityRequestStatusRequest) (*common.FacilityRequest, error) {
-	result, err := fs.dbs.GetFacilityRequest(in.RequestId)
+	userID, err := authenticatedUserID(ctx)
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
 
-	isAbleToviewRequest, permission, err := isAbleToViewFacilityRequest(fs, in.UserId, result)
+	result, err := fs.dbs.GetFacilityRequest(ctx, in.RequestId)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	isAbleToviewRequest, permission, err := isAbleToViewFacilityRequest(ctx, fs, userID, result)
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
@@ -198,14 +454,56 @@ func (fs *FacilityServer) GetFacilityRequestStatus(ctx context.Context, in *faci
 	return result, nil
 }
 
+// GetFacilityRequestHistory is a function to get status transitions of facility request
+func (fs *FacilityServer) GetFacilityRequestHistory(ctx context.Context, in *facility.GetFacilityRequestHistoryRequest) (*facility.GetFacilityRequestHistoryResponse, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	facilityRequest, err := fs.dbs.GetFacilityRequest(ctx, in.RequestId)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	isAbleToviewRequest, permission, err := isAbleToViewFacilityRequest(ctx, fs, userID, facilityRequest)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	if !isAbleToviewRequest {
+		return nil, status.Error(codes.PermissionDenied, (&typing.PermissionError{Type: permission}).Error())
+	}
+
+	history, err := fs.dbs.GetFacilityRequestHistory(ctx, in.RequestId)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	decisions, err := fs.dbs.GetApprovalDecisions(ctx, in.RequestId)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	return &facility.GetFacilityRequestHistoryResponse{
+		History:   history,
+		Decisions: decisions,
+	}, nil
+}
+
 // GetFacilityRequestStatusFull is a function to get facility request’s of the event
 func (fs *FacilityServer) GetFacilityRequestStatusFull(ctx context.Context, in *facility.GetFacilityRequestStatusFullRequest) (*facility.FacilityRequestWithFacilityInfo, error) {
-	result, err := fs.dbs.GetFacilityRequestStatusFull(in.RequestId)
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	result, err := fs.dbs.GetFacilityRequestStatusFull(ctx, in.RequestId)
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
 
-	isAbleToviewRequest, permission, err := isAbleToViewFacilityRequestFull(fs, in.UserId, result)
+	isAbleToviewRequest, permission, err := isAbleToViewFacilityRequestFull(ctx, fs, userID, result)
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
@@ -217,33 +515,468 @@ func (fs *FacilityServer) GetFacilityRequestStatusFull(ctx context.Context, in *
 	return result, nil
 }
 
-// GetAvailableTimeOfFacility is a function to get available of facility will ignore hours and seconds in start/finish input
+// GetAvailableTimeOfFacility is a function to get available of facility will ignore hours and seconds in start/finish input, days are in the facility's time zone
 func (fs *FacilityServer) GetAvailableTimeOfFacility(ctx context.Context, in *facility.GetAvailableTimeOfFacilityRequest) (*facility.GetAvailableTimeOfFacilityResponse, error) {
-	startTime, _ := ptypes.Timestamp(in.Start)
-	finishTime, _ := ptypes.Timestamp(in.End)
-	err := isAbleToGetAvailableTimeOfFacility(startTime, finishTime)
+	facilityInfo, err := getFacilityInfoWithRequests(ctx, fs, in.FacilityId, in.Start, in.End)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	operatingHours := facilityInfo.Info.OperatingHours
+	slotMinutes := facilityInfo.Info.SlotMinutes
+	if in.Mode == facility.AvailabilityMode_INTERVALS {
+		now := time.Now().In(facilityInfo.Start.Location())
+		return &facility.GetAvailableTimeOfFacilityResponse{
+			Intervals:   generateFacilityFreeBusyResult(facilityInfo.Start, facilityInfo.Finish, now, operatingHours, facilityInfo.Overrides, facilityInfo.Requests, facilityInfo.BusyBlocks),
+			SlotMinutes: helper.SlotMinutesOrDefault(slotMinutes),
+		}, nil
+	}
+
+	emptyResultArray := createResultEmptyArray(facilityInfo.Start, facilityInfo.Finish, operatingHours, facilityInfo.Overrides, slotMinutes)
+	return generateFacilityAvailabilityResult(emptyResultArray, facilityInfo.Start, operatingHours, facilityInfo.Overrides, facilityInfo.Requests, facilityInfo.BusyBlocks, slotMinutes), nil
+}
+
+// CreateFacility is a function to create facility owned by organization
+func (fs *FacilityServer) CreateFacility(ctx context.Context, in *facility.CreateFacilityReq) (*common.Facility, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	permission := common.Permission_UPDATE_FACILITY
+	isPermission, err := hasPermission(ctx, fs, userID, in.OrganizationId, permission)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	if !isPermission {
+		return nil, status.Error(codes.PermissionDenied, (&typing.PermissionError{Type: permission}).Error())
+	}
+
+	result, err := fs.dbs.CreateFacility(ctx, &common.Facility{
+		OrganizationId: in.OrganizationId,
+		Name:           in.Name,
+		Latitude:       in.Latitude,
+		Longitude:      in.Longitude,
+		OperatingHours: in.OperatingHours,
+		Description:    in.Description,
+		SlotMinutes:    in.SlotMinutes,
+		TimeZone:       in.TimeZone,
+		Capacity:       in.Capacity,
+		Attributes:     in.Attributes,
+		ApprovalChain:  in.ApprovalChain,
+		Quota:          in.Quota,
+		BookingWindow:  in.BookingWindow,
+	})
+
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
 
-	facility, err := getFacilityInfoWithRequests(fs, in.FacilityId, in.Start, in.End)
+	return result, nil
+}
+
+// UpdateFacility is a function to update facility’s information by id
+func (fs *FacilityServer) UpdateFacility(ctx context.Context, in *facility.UpdateFacilityRequest) (*common.Facility, error) {
+	userID, err := authenticatedUserID(ctx)
 	if err != nil {
 		return nil, status.Error(err.Code(), err.Error())
 	}
 
-	operatingHours := map[int32]*common.OperatingHour{}
-	for _, operatingHour := range facility.Info.OperatingHours {
-		operatingHours[int32(operatingHour.Day.Number())] = operatingHour
+	isConditionPassed, err := isAbleToUpdateFacility(ctx, fs, userID, in.FacilityId)
+	if !isConditionPassed || err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	result, err := fs.dbs.UpdateFacility(ctx, &common.Facility{
+		Id:             in.FacilityId,
+		Name:           in.Name,
+		Latitude:       in.Latitude,
+		Longitude:      in.Longitude,
+		OperatingHours: in.OperatingHours,
+		Description:    in.Description,
+		SlotMinutes:    in.SlotMinutes,
+		TimeZone:       in.TimeZone,
+		Capacity:       in.Capacity,
+		Attributes:     in.Attributes,
+		ApprovalChain:  in.ApprovalChain,
+		Quota:          in.Quota,
+		BookingWindow:  in.BookingWindow,
+	})
+
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
 	}
 
-	emptyResultArray := createResultEmptyArray(startTime, finishTime, operatingHours)
-	return generateFacilityAvailabilityResult(emptyResultArray, startTime, operatingHours, facility.Requests), nil
+	return result, nil
+}
+
+// DeleteFacility is a function to delete facility by id
+func (fs *FacilityServer) DeleteFacility(ctx context.Context, in *facility.DeleteFacilityRequest) (*common.Result, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	isConditionPassed, err := isAbleToUpdateFacility(ctx, fs, userID, in.FacilityId)
+	if !isConditionPassed || err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	err = fs.dbs.DeleteFacility(ctx, in.FacilityId)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	description := fmt.Sprintf("Facility ID: %d has been deleted", in.FacilityId)
+	return &common.Result{
+		IsOk:        true,
+		Description: description,
+	}, nil
+}
+
+// WatchFacilityRequests is a function to stream facility requests matching the filters whenever they are created or their status is changed
+func (fs *FacilityServer) WatchFacilityRequests(in *facility.WatchFacilityRequestsRequest, stream facility.FacilityService_WatchFacilityRequestsServer) error {
+	ctx := stream.Context()
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return status.Error(err.Code(), err.Error())
+	}
+
+	isConditionPassed, err := isAbleToWatchFacilityRequests(ctx, fs, userID, in)
+	if !isConditionPassed || err != nil {
+		return status.Error(err.Code(), err.Error())
+	}
+
+	changes, unsubscribe, err := fs.dbs.SubscribeFacilityRequestChanges()
+	if err != nil {
+		return status.Error(err.Code(), err.Error())
+	}
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case change, ok := <-changes:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "Watcher is too slow to receive changes")
+			}
+			if !isMatchingFacilityRequestChange(in, change) {
+				continue
+			}
+
+			result, err := fs.dbs.GetFacilityRequest(ctx, change.ID)
+			if err != nil {
+				return status.Error(err.Code(), err.Error())
+			}
+			if err := stream.Send(result); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// SetOperatingHourOverride is a function to close facility or change its hours on a date
+func (fs *FacilityServer) SetOperatingHourOverride(ctx context.Context, in *facility.SetOperatingHourOverrideRequest) (*common.OperatingHourOverride, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	isConditionPassed, err := isAbleToUpdateFacility(ctx, fs, userID, in.Override.GetFacilityId())
+	if !isConditionPassed || err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	result, err := fs.dbs.SetOperatingHourOverride(ctx, in.Override)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	return result, nil
+}
+
+// DeleteOperatingHourOverride is a function to restore weekly operating hour of facility on a date
+func (fs *FacilityServer) DeleteOperatingHourOverride(ctx context.Context, in *facility.DeleteOperatingHourOverrideRequest) (*common.Result, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	isConditionPassed, err := isAbleToUpdateFacility(ctx, fs, userID, in.FacilityId)
+	if !isConditionPassed || err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	err = fs.dbs.DeleteOperatingHourOverride(ctx, in.FacilityId, in.Date)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	description := fmt.Sprintf("Override of facility ID: %d on %s has been deleted", in.FacilityId, in.Date)
+	return &common.Result{
+		IsOk:        true,
+		Description: description,
+	}, nil
+}
+
+// GetOperatingHourOverrideList is a function to list overrides and holidays of facility from start to end date
+func (fs *FacilityServer) GetOperatingHourOverrideList(ctx context.Context, in *facility.GetOperatingHourOverrideListRequest) (*facility.GetOperatingHourOverrideListResponse, error) {
+	facilityInfo, err := fs.dbs.GetFacilityInfo(ctx, in.FacilityId)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	location, locationErr := time.LoadLocation(facilityInfo.TimeZone)
+	if locationErr != nil {
+		return nil, status.Error(codes.DataLoss, locationErr.Error())
+	}
+
+	startTime := in.Start.AsTime().In(location)
+	finishTime := in.End.AsTime().In(location)
+	overrides, err := fs.dbs.GetOperatingHourOverrides(ctx, in.FacilityId, startTime, finishTime)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	result := make([]*common.OperatingHourOverride, 0, len(overrides))
+	for _, override := range overrides {
+		result = append(result, override)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+
+	return &facility.GetOperatingHourOverrideListResponse{
+		Overrides: result,
+	}, nil
+}
+
+// ImportHolidayCalendar is a function to replace holidays closing every facility of organization
+func (fs *FacilityServer) ImportHolidayCalendar(ctx context.Context, in *facility.ImportHolidayCalendarRequest) (*common.Result, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	permission := common.Permission_UPDATE_FACILITY
+	isPermission, err := hasPermission(ctx, fs, userID, in.OrganizationId, permission)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	if !isPermission {
+		return nil, status.Error(codes.PermissionDenied, (&typing.PermissionError{Type: permission}).Error())
+	}
+
+	err = fs.dbs.ImportHolidayCalendar(ctx, in.OrganizationId, in.CalendarName, in.Holidays)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	description := fmt.Sprintf("%d holidays of calendar %s have been imported", len(in.Holidays), in.CalendarName)
+	return &common.Result{
+		IsOk:        true,
+		Description: description,
+	}, nil
+}
+
+// GetQuotaUsage is a function to get usage and remaining allowance of facility's quota by the requesting organization in a week
+func (fs *FacilityServer) GetQuotaUsage(ctx context.Context, in *facility.GetQuotaUsageRequest) (*facility.GetQuotaUsageResponse, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	facilityInfo, err := isAbleToGetQuotaUsage(ctx, fs, userID, in)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	location, locationErr := time.LoadLocation(facilityInfo.TimeZone)
+	if locationErr != nil {
+		return nil, status.Error(codes.DataLoss, locationErr.Error())
+	}
+
+	week := time.Now()
+	if in.Week != nil {
+		week = in.Week.AsTime()
+	}
+	weekStart := helper.WeekStart(week.In(location))
+	weekFinish := weekStart.AddDate(0, 0, 7)
+
+	usage, err := fs.dbs.GetQuotaUsage(ctx, in.FacilityId, in.OrganizationId, weekStart, weekFinish)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	result := &facility.GetQuotaUsageResponse{
+		Quota:           facilityInfo.Quota,
+		WeekStart:       timestamppb.New(weekStart),
+		WeekFinish:      timestamppb.New(weekFinish),
+		BookedMinutes:   usage.BookedMinutes,
+		PendingRequests: usage.PendingRequests,
+	}
+	if maxHours := facilityInfo.Quota.GetMaxHoursPerWeek(); maxHours > 0 {
+		result.RemainingMinutes = &wrapperspb.Int64Value{Value: remainingQuota(int64(maxHours)*60, usage.BookedMinutes)}
+	}
+	if maxPending := facilityInfo.Quota.GetMaxPendingRequests(); maxPending > 0 {
+		result.RemainingPendingRequests = &wrapperspb.Int64Value{Value: remainingQuota(int64(maxPending), usage.PendingRequests)}
+	}
+
+	return result, nil
+}
+
+// SetOrganizationBookingWindow is a function to set default booking window of facilities owned by organization
+func (fs *FacilityServer) SetOrganizationBookingWindow(ctx context.Context, in *facility.SetOrganizationBookingWindowRequest) (*common.BookingWindow, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	permission := common.Permission_UPDATE_FACILITY
+	isPermission, err := hasPermission(ctx, fs, userID, in.OrganizationId, permission)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	if !isPermission {
+		return nil, status.Error(codes.PermissionDenied, (&typing.PermissionError{Type: permission}).Error())
+	}
+
+	result, err := fs.dbs.SetOrganizationBookingWindow(ctx, in.OrganizationId, in.BookingWindow)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	return result, nil
+}
+
+// CreateCalendarFeed is a function to create iCalendar feed of facility or every facility of organization, its url is only returned here
+func (fs *FacilityServer) CreateCalendarFeed(ctx context.Context, in *facility.CreateCalendarFeedRequest) (*facility.CalendarFeed, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	isConditionPassed, err := isAbleToCreateCalendarFeed(ctx, fs, userID, in)
+	if !isConditionPassed || err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	result, err := fs.dbs.CreateCalendarFeed(ctx, in.OrganizationId, in.FacilityId, userID)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	return result, nil
+}
+
+// RevokeCalendarFeed is a function to revoke iCalendar feed by id
+func (fs *FacilityServer) RevokeCalendarFeed(ctx context.Context, in *facility.RevokeCalendarFeedRequest) (*common.Result, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	isConditionPassed, err := isAbleToRevokeCalendarFeed(ctx, fs, userID, in)
+	if !isConditionPassed || err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	err = fs.dbs.RevokeCalendarFeed(ctx, in.FeedId)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	return &common.Result{
+		IsOk:        true,
+		Description: fmt.Sprintf("Calendar feed ID: %d has been revoked", in.FeedId),
+	}, nil
+}
+
+// GetCalendarFeedList is a function to list iCalendar feeds of organization which are not revoked
+func (fs *FacilityServer) GetCalendarFeedList(ctx context.Context, in *facility.GetCalendarFeedListRequest) (*facility.GetCalendarFeedListResponse, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	permission := common.Permission_UPDATE_FACILITY
+	isPermission, err := hasPermission(ctx, fs, userID, in.OrganizationId, permission)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	if !isPermission {
+		return nil, status.Error(codes.PermissionDenied, (&typing.PermissionError{Type: permission}).Error())
+	}
+
+	result, err := fs.dbs.GetCalendarFeedList(ctx, in.OrganizationId)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	return &facility.GetCalendarFeedListResponse{
+		Feeds: result,
+	}, nil
+}
+
+// ImportBusyBlocks is a function to replace busy blocks of facility from the source with events of iCalendar, from now until a year ahead
+func (fs *FacilityServer) ImportBusyBlocks(ctx context.Context, in *facility.ImportBusyBlocksRequest) (*facility.ImportBusyBlocksResponse, error) {
+	userID, err := authenticatedUserID(ctx)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	facilityInfo, err := isAbleToImportBusyBlocks(ctx, fs, userID, in)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	location, locationErr := time.LoadLocation(facilityInfo.TimeZone)
+	if locationErr != nil {
+		return nil, status.Error(codes.DataLoss, locationErr.Error())
+	}
+
+	now := time.Now()
+	busyBlocks, err := parseCalendarBusyBlocks(in.Calendar, location, now, now.AddDate(0, 0, calendarFutureDays))
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	result, err := fs.dbs.ImportBusyBlocks(ctx, in.FacilityId, in.Source, busyBlocks)
+	if err != nil {
+		return nil, status.Error(err.Code(), err.Error())
+	}
+
+	return &facility.ImportBusyBlocksResponse{
+		BusyBlocks: result,
+	}, nil
+}
+
+// serviceTimeout is function to read deadline of calls to a service such as 3s from the environment variable
+func serviceTimeout(name string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultServiceTimeout
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Failed to parse %s: %v", name, err)
+	}
+	return timeout
 }
 
 func (fs *FacilityServer) connectToGRPCClients() {
 	accountPath := os.Getenv("HTS_SVC_ACCOUNT")
 	participantPath := os.Getenv("HTS_SVC_PARTICIPANT")
 	organizerPart := os.Getenv("HTS_SVC_ORGANIZER")
+	fs.timeouts = ServiceTimeouts{
+		Account:     serviceTimeout("HTS_SVC_ACCOUNT_TIMEOUT"),
+		Participant: serviceTimeout("HTS_SVC_PARTICIPANT_TIMEOUT"),
+		Organizer:   serviceTimeout("HTS_SVC_ORGANIZER_TIMEOUT"),
+	}
 
 	// Disable transport security is intentional
 	opts := []grpc.DialOption{grpc.WithInsecure()}
@@ -284,7 +1017,14 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
-	s := grpc.NewServer()
+	verifier, err := auth.NewVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load token key: %v", err)
+	}
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(verifier.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(verifier.StreamServerInterceptor()),
+	)
 
 	facilityServer := &FacilityServer{}
 
@@ -295,7 +1035,19 @@ func main() {
 	db.ConnectToDB()
 	facilityServer.dbs = db
 
+	permissions, err := cache.NewPermissionCacheFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure permission cache: %v", err)
+	}
+	facilityServer.permissions = permissions
+
 	facilityServer.connectToGRPCClients()
+	if adminPort := os.Getenv("ADMIN_PORT"); adminPort != "" {
+		go facilityServer.serveAdmin(adminPort)
+	}
+	if calendarPort := os.Getenv("CALENDAR_PORT"); calendarPort != "" {
+		go facilityServer.serveCalendarFeeds(calendarPort)
+	}
 	facility.RegisterFacilityServiceServer(s, facilityServer)
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
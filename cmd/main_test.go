@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	common "onepass.app/facility/hts/common"
+	facility "onepass.app/facility/hts/facility"
+	auth "onepass.app/facility/internal/auth"
+	cache "onepass.app/facility/internal/cache"
+	typing "onepass.app/facility/internal/typing"
 )
 
 func TestSomething2(t *testing.T) {
@@ -19,3 +27,23 @@ func TestSomething2(t *testing.T) {
 	assert.Empty(t, a, "A is empty")
 	// log.Println(a)
 }
+
+func TestHandlerActsAsAuthenticatedUser(t *testing.T) {
+	assert := assert.New(t)
+
+	// only the forged user may update facilities of the organization, so acting as it would reach the database
+	permissions := cache.NewPermissionCache(time.Minute, 10)
+	for userID, isAllowed := range map[int64]bool{7: true, 42: false} {
+		isAllowed := isAllowed
+		key := cache.PermissionKey{UserID: userID, OrganizationID: 1, Permission: common.Permission_UPDATE_FACILITY}
+		permissions.Get(context.Background(), key, func() (bool, typing.CustomError) { return isAllowed, nil })
+	}
+	fs := &FacilityServer{permissions: permissions}
+	in := &facility.GetFacilityRequestListRequest{UserId: 7, OrganizationId: 1}
+
+	_, err := fs.GetFacilityRequestList(auth.NewContext(context.Background(), 42), in)
+	assert.Equal(codes.PermissionDenied, status.Code(err))
+
+	_, err = fs.GetFacilityRequestList(context.Background(), in)
+	assert.Equal(codes.Unauthenticated, status.Code(err))
+}
@@ -1,9 +1,40 @@
 package main
 
-import common "onepass.app/facility/hts/common"
+import (
+	"time"
+
+	common "onepass.app/facility/hts/common"
+)
 
 // FacilityInfoWithRequest is a struct to combine facility info and request
 type FacilityInfoWithRequest struct {
-	Info     *common.Facility
-	Requests []*common.FacilityRequest
+	Info       *common.Facility
+	Requests   []*common.FacilityRequest
+	BusyBlocks []*common.BusyBlock
+	Overrides  map[string]*common.OperatingHourOverride
+	Start      time.Time
+	Finish     time.Time
+}
+
+// ServiceTimeouts is a struct of deadlines of calls to each service the facility service depends on
+type ServiceTimeouts struct {
+	Account     time.Duration
+	Participant time.Duration
+	Organizer   time.Duration
+}
+
+// CalendarEntry is a struct of approved facility request in calendar feed
+type CalendarEntry struct {
+	Request   *common.FacilityRequest
+	Facility  *common.Facility
+	EventName string
+}
+
+// CalendarProperty is a struct of iCalendar property value and its parameters
+type CalendarProperty struct {
+	Params map[string]string
+	Value  string
 }
+
+// CalendarEvent is a struct of properties of iCalendar VEVENT keyed by name
+type CalendarEvent map[string][]*CalendarProperty
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	typing "onepass.app/facility/internal/typing"
+)
+
+// authorizationKey is gRPC metadata key of the bearer token
+const authorizationKey = "authorization"
+
+// userIDField is name of the request field identifying the acting user
+const userIDField = "user_id"
+
+// authenticate is function to verify bearer token in metadata of the call, false means no token is sent
+func (verifier *Verifier) authenticate(ctx context.Context) (int64, bool, typing.CustomError) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(authorizationKey)
+	if len(values) == 0 {
+		return 0, false, nil
+	}
+
+	const prefix = "bearer "
+	if len(values[0]) <= len(prefix) || !strings.EqualFold(values[0][:len(prefix)], prefix) {
+		return 0, false, &typing.AuthenticationError{Name: "Authorization must be a bearer token"}
+	}
+	userID, err := verifier.Verify(values[0][len(prefix):], time.Now())
+	if err != nil {
+		return 0, false, err
+	}
+	return userID, true, nil
+}
+
+// checkUserID is function to reject requests whose user_id is not the authenticated user while clients still send it,
+// handlers act as the user of the context so the request is never rewritten and anonymous calls are left to them
+func checkUserID(request interface{}, userID int64, isAuthenticated bool) typing.CustomError {
+	message, ok := request.(proto.Message)
+	if !ok || !isAuthenticated {
+		return nil
+	}
+	reflection := message.ProtoReflect()
+	field := reflection.Descriptor().Fields().ByName(userIDField)
+	if field == nil || field.Kind() != protoreflect.Int64Kind {
+		return nil
+	}
+
+	if bodyUserID := reflection.Get(field).Int(); bodyUserID != 0 && bodyUserID != userID {
+		return &typing.IdentityError{UserID: bodyUserID, TokenUserID: userID}
+	}
+	return nil
+}
+
+// UnaryServerInterceptor is function to authenticate unary calls and put the user on their context
+func (verifier *Verifier) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		userID, isAuthenticated, err := verifier.authenticate(ctx)
+		if err != nil {
+			return nil, status.Error(err.Code(), err.Error())
+		}
+		if err := checkUserID(req, userID, isAuthenticated); err != nil {
+			return nil, status.Error(err.Code(), err.Error())
+		}
+
+		if isAuthenticated {
+			ctx = NewContext(ctx, userID)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authenticatedStream is server stream carrying the authenticated user, its received requests are checked against the user
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx             context.Context
+	userID          int64
+	isAuthenticated bool
+}
+
+// Context is function to get context of the stream with the authenticated user
+func (stream *authenticatedStream) Context() context.Context {
+	return stream.ctx
+}
+
+// RecvMsg is function to receive request of the stream and check it against the authenticated user
+func (stream *authenticatedStream) RecvMsg(m interface{}) error {
+	if err := stream.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if err := checkUserID(m, stream.userID, stream.isAuthenticated); err != nil {
+		return status.Error(err.Code(), err.Error())
+	}
+	return nil
+}
+
+// StreamServerInterceptor is function to authenticate streaming calls and put the user on their context
+func (verifier *Verifier) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		userID, isAuthenticated, err := verifier.authenticate(ss.Context())
+		if err != nil {
+			return status.Error(err.Code(), err.Error())
+		}
+
+		ctx := ss.Context()
+		if isAuthenticated {
+			ctx = NewContext(ctx, userID)
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx, userID: userID, isAuthenticated: isAuthenticated})
+	}
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	typing "onepass.app/facility/internal/typing"
+)
+
+// clockSkew is how much clocks of the issuer and this service may differ when checking exp and nbf
+const clockSkew = time.Minute
+
+// Verifier is for verifying HS256 or RS256 JSON Web Token with a local key, a nil key disables its algorithm
+type Verifier struct {
+	Secret    []byte
+	PublicKey *rsa.PublicKey
+}
+
+// tokenHeader is JOSE header of JSON Web Token
+type tokenHeader struct {
+	Alg string `json:"alg"`
+}
+
+// tokenClaims is claims of JSON Web Token, the subject is ID of the user
+type tokenClaims struct {
+	Subject   string   `json:"sub"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+}
+
+// contextKey is key of the authenticated user in context
+type contextKey struct{}
+
+// NewVerifierFromEnv is function to create verifier from AUTH_JWT_SECRET and PEM file at AUTH_JWT_PUBLIC_KEY_PATH
+func NewVerifierFromEnv() (*Verifier, error) {
+	verifier := &Verifier{}
+	if secret := os.Getenv("AUTH_JWT_SECRET"); secret != "" {
+		verifier.Secret = []byte(secret)
+	}
+	if path := os.Getenv("AUTH_JWT_PUBLIC_KEY_PATH"); path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if verifier.PublicKey, err = ParseRSAPublicKey(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if verifier.Secret == nil && verifier.PublicKey == nil {
+		return nil, errors.New("AUTH_JWT_SECRET or AUTH_JWT_PUBLIC_KEY_PATH must be set")
+	}
+	return verifier, nil
+}
+
+// ParseRSAPublicKey is function to parse PEM encoded PKIX or PKCS #1 RSA public key
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("public key must be PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key must be RSA")
+	}
+	return rsaKey, nil
+}
+
+// Verify is function to check signature and lifetime of the token and get ID of its user,
+// the algorithm must be the one of a configured key so an RS256 public key can not be used as HS256 secret
+func (verifier *Verifier) Verify(token string, now time.Time) (int64, typing.CustomError) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, &typing.AuthenticationError{Name: "Token must have 3 parts"}
+	}
+
+	var header tokenHeader
+	if err := decodeTokenPart(parts[0], &header); err != nil {
+		return 0, err
+	}
+	signature, decodeErr := base64.RawURLEncoding.DecodeString(parts[2])
+	if decodeErr != nil {
+		return 0, &typing.AuthenticationError{Name: "Token signature must be base64url encoded"}
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	switch {
+	case header.Alg == "HS256" && verifier.Secret != nil:
+		mac := hmac.New(sha256.New, verifier.Secret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return 0, &typing.AuthenticationError{Name: "Token signature is invalid"}
+		}
+	case header.Alg == "RS256" && verifier.PublicKey != nil:
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(verifier.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return 0, &typing.AuthenticationError{Name: "Token signature is invalid"}
+		}
+	default:
+		return 0, &typing.AuthenticationError{Name: "Token algorithm " + header.Alg + " is not accepted"}
+	}
+
+	var claims tokenClaims
+	if err := decodeTokenPart(parts[1], &claims); err != nil {
+		return 0, err
+	}
+	if claims.ExpiresAt == nil {
+		return 0, &typing.AuthenticationError{Name: "Token must have exp"}
+	}
+	if now.Add(-clockSkew).After(time.Unix(int64(*claims.ExpiresAt), 0)) {
+		return 0, &typing.AuthenticationError{Name: "Token is expired"}
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(time.Unix(int64(*claims.NotBefore), 0)) {
+		return 0, &typing.AuthenticationError{Name: "Token is not valid yet"}
+	}
+
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || userID <= 0 {
+		return 0, &typing.AuthenticationError{Name: "Token subject must be user ID"}
+	}
+	return userID, nil
+}
+
+// decodeTokenPart is function to decode base64url JSON part of the token
+func decodeTokenPart(part string, v interface{}) typing.CustomError {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return &typing.AuthenticationError{Name: "Token must be base64url encoded"}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return &typing.AuthenticationError{Name: "Token must be JSON"}
+	}
+	return nil
+}
+
+// NewContext is function to attach ID of the authenticated user to context
+func NewContext(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, contextKey{}, userID)
+}
+
+// UserIDFromContext is function to get ID of the authenticated user, false means the caller is anonymous
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(contextKey{}).(int64)
+	return userID, ok
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	facility "onepass.app/facility/hts/facility"
+)
+
+func encodeTokenPart(part string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(part))
+}
+
+func signHS256(secret string, header string, claims string) string {
+	signed := encodeTokenPart(header) + "." + encodeTokenPart(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyHS256(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(1614556800, 0)
+	verifier := &Verifier{Secret: []byte("secret")}
+	header := `{"alg":"HS256","typ":"JWT"}`
+
+	userID, err := verifier.Verify(signHS256("secret", header, `{"sub":"42","exp":1614560400}`), now)
+	assert.Nil(err)
+	assert.Equal(int64(42), userID)
+
+	_, err = verifier.Verify(signHS256("other", header, `{"sub":"42","exp":1614560400}`), now)
+	assert.Equal("authentication error: Token signature is invalid", err.Error())
+	assert.Equal(codes.Unauthenticated, err.Code())
+
+	_, err = verifier.Verify(signHS256("secret", header, `{"sub":"42","exp":1614553200}`), now)
+	assert.Equal("authentication error: Token is expired", err.Error())
+
+	_, err = verifier.Verify(signHS256("secret", header, `{"sub":"42"}`), now)
+	assert.Equal("authentication error: Token must have exp", err.Error())
+
+	_, err = verifier.Verify(signHS256("secret", header, `{"sub":"42","exp":1614564000,"nbf":1614560400}`), now)
+	assert.Equal("authentication error: Token is not valid yet", err.Error())
+
+	_, err = verifier.Verify(signHS256("secret", header, `{"sub":"someone","exp":1614560400}`), now)
+	assert.Equal("authentication error: Token subject must be user ID", err.Error())
+
+	unsigned := encodeTokenPart(`{"alg":"none"}`) + "." + encodeTokenPart(`{"sub":"42","exp":1614560400}`) + "."
+	_, err = verifier.Verify(unsigned, now)
+	assert.Equal("authentication error: Token algorithm none is not accepted", err.Error())
+}
+
+func TestVerifyRS256(t *testing.T) {
+	assert := assert.New(t)
+
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	publicKeyDER, _ := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	publicKey, parseErr := ParseRSAPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER}))
+	assert.Nil(parseErr)
+
+	signed := encodeTokenPart(`{"alg":"RS256"}`) + "." + encodeTokenPart(`{"sub":"7","exp":1614560400}`)
+	digest := sha256.Sum256([]byte(signed))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	token := signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	now := time.Unix(1614556800, 0)
+	userID, err := (&Verifier{PublicKey: publicKey}).Verify(token, now)
+	assert.Nil(err)
+	assert.Equal(int64(7), userID)
+
+	_, err = (&Verifier{Secret: []byte("secret")}).Verify(token, now)
+	assert.Equal("authentication error: Token algorithm RS256 is not accepted", err.Error())
+}
+
+func TestCheckUserID(t *testing.T) {
+	assert := assert.New(t)
+
+	in := &facility.ApproveFacilityRequestRequest{RequestId: 1}
+	assert.Nil(checkUserID(in, 42, true))
+	assert.Equal(int64(0), in.UserId)
+	assert.Nil(checkUserID(&facility.ApproveFacilityRequestRequest{UserId: 42}, 42, true))
+
+	err := checkUserID(&facility.ApproveFacilityRequestRequest{UserId: 7, RequestId: 1}, 42, true)
+	assert.Equal(codes.PermissionDenied, err.Code())
+	assert.Equal("identity error: user ID 7 does not match authenticated user ID 42", err.Error())
+
+	assert.Nil(checkUserID(&facility.ApproveFacilityRequestRequest{UserId: 42}, 0, false))
+	assert.Nil(checkUserID(&facility.GetFacilityInfoRequest{FacilityId: 1}, 42, true))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	assert := assert.New(t)
+
+	verifier := &Verifier{Secret: []byte("secret")}
+	interceptor := verifier.UnaryServerInterceptor()
+	token := signHS256("secret", `{"alg":"HS256"}`, `{"sub":"42","exp":4102444800}`)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		userID, _ := UserIDFromContext(ctx)
+		return userID, nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	userID, err := interceptor(ctx, &facility.ApproveFacilityRequestRequest{UserId: 42}, &grpc.UnaryServerInfo{}, handler)
+	assert.Nil(err)
+	assert.Equal(int64(42), userID)
+
+	_, err = interceptor(ctx, &facility.ApproveFacilityRequestRequest{UserId: 7}, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(codes.PermissionDenied, status.Code(err))
+
+	// anonymous calls reach handlers without a user, which refuse to act for user_id of the request
+	userID, err = interceptor(context.Background(), &facility.ApproveFacilityRequestRequest{UserId: 42}, &grpc.UnaryServerInfo{}, handler)
+	assert.Nil(err)
+	assert.Equal(int64(0), userID)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", token))
+	_, err = interceptor(ctx, &facility.GetFacilityInfoRequest{}, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	common "onepass.app/facility/hts/common"
+	typing "onepass.app/facility/internal/typing"
+)
+
+// DefaultPermissionTTL and DefaultPermissionSize are used when PERMISSION_CACHE_TTL and PERMISSION_CACHE_SIZE are not set
+const (
+	DefaultPermissionTTL  = 30 * time.Second
+	DefaultPermissionSize = 10000
+)
+
+// PermissionKey is key of a cached permission check
+type PermissionKey struct {
+	UserID         int64
+	OrganizationID int64
+	Permission     common.Permission
+}
+
+// PermissionStats is hit and miss metrics of permission cache, coalesced lookups waited for another lookup of the same key
+type PermissionStats struct {
+	Hits      int64
+	Misses    int64
+	Coalesced int64
+	Evictions int64
+	Size      int
+}
+
+// permissionEntry is cached result of a permission check
+type permissionEntry struct {
+	key       PermissionKey
+	isAllowed bool
+	expiresAt time.Time
+}
+
+// permissionCall is in-flight permission check shared by identical lookups
+type permissionCall struct {
+	done      chan struct{}
+	isAllowed bool
+	err       typing.CustomError
+}
+
+// PermissionCache is a TTL cache of permission checks evicting the least recently used entry beyond its size,
+// a nil cache loads every lookup
+type PermissionCache struct {
+	ttl   time.Duration
+	size  int
+	now   func() time.Time
+	mutex sync.Mutex
+	// order has the most recently used entry at front
+	order   *list.List
+	entries map[PermissionKey]*list.Element
+	calls   map[PermissionKey]*permissionCall
+	stats   PermissionStats
+}
+
+// NewPermissionCache is function to create permission cache keeping results for ttl and at most size entries
+func NewPermissionCache(ttl time.Duration, size int) *PermissionCache {
+	return &PermissionCache{
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+		order:   list.New(),
+		entries: map[PermissionKey]*list.Element{},
+		calls:   map[PermissionKey]*permissionCall{},
+	}
+}
+
+// NewPermissionCacheFromEnv is function to create permission cache from PERMISSION_CACHE_TTL such as 30s and PERMISSION_CACHE_SIZE
+func NewPermissionCacheFromEnv() (*PermissionCache, error) {
+	ttl := DefaultPermissionTTL
+	if value := os.Getenv("PERMISSION_CACHE_TTL"); value != "" {
+		var err error
+		if ttl, err = time.ParseDuration(value); err != nil {
+			return nil, err
+		}
+	}
+
+	size := DefaultPermissionSize
+	if value := os.Getenv("PERMISSION_CACHE_SIZE"); value != "" {
+		var err error
+		if size, err = strconv.Atoi(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewPermissionCache(ttl, size), nil
+}
+
+// Get is function to get cached result of the permission check or load it, concurrent lookups of the same key share one load
+// and errors are not cached, the load is detached from callers so a cancelled caller does not fail the others sharing it
+func (cache *PermissionCache) Get(ctx context.Context, key PermissionKey, load func() (bool, typing.CustomError)) (bool, typing.CustomError) {
+	if cache == nil {
+		return load()
+	}
+
+	cache.mutex.Lock()
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*permissionEntry)
+		if cache.now().Before(entry.expiresAt) {
+			cache.order.MoveToFront(element)
+			cache.stats.Hits++
+			cache.mutex.Unlock()
+			return entry.isAllowed, nil
+		}
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+	}
+
+	call, ok := cache.calls[key]
+	if ok {
+		cache.stats.Coalesced++
+	} else {
+		cache.stats.Misses++
+		call = &permissionCall{done: make(chan struct{})}
+		cache.calls[key] = call
+		go cache.load(key, call, load)
+	}
+	cache.mutex.Unlock()
+
+	select {
+	case <-call.done:
+		return call.isAllowed, call.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return false, &typing.ContextError{Name: "Permission check", StatusCode: codes.DeadlineExceeded}
+		}
+		return false, &typing.ContextError{Name: "Permission check", StatusCode: codes.Canceled}
+	}
+}
+
+// load is function to run the permission check shared by lookups of the key and store its result
+func (cache *PermissionCache) load(key PermissionKey, call *permissionCall, load func() (bool, typing.CustomError)) {
+	defer close(call.done)
+	call.isAllowed, call.err = load()
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	// a flush during the load replaces calls, then the result may be stale and is not stored
+	if cache.calls[key] == call {
+		delete(cache.calls, key)
+		if call.err == nil {
+			cache.store(key, call.isAllowed)
+		}
+	}
+}
+
+// store is function to add result to the cache and evict the least recently used entries beyond its size, mutex must be held
+func (cache *PermissionCache) store(key PermissionKey, isAllowed bool) {
+	if cache.ttl <= 0 || cache.size <= 0 {
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(&permissionEntry{key: key, isAllowed: isAllowed, expiresAt: cache.now().Add(cache.ttl)})
+	for cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*permissionEntry).key)
+		cache.stats.Evictions++
+	}
+}
+
+// Flush is function to drop every cached result, loads in flight are not stored
+func (cache *PermissionCache) Flush() {
+	if cache == nil {
+		return
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.order.Init()
+	cache.entries = map[PermissionKey]*list.Element{}
+	cache.calls = map[PermissionKey]*permissionCall{}
+}
+
+// Stats is function to get metrics of the cache
+func (cache *PermissionCache) Stats() PermissionStats {
+	if cache == nil {
+		return PermissionStats{}
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	stats := cache.stats
+	stats.Size = cache.order.Len()
+	return stats
+}
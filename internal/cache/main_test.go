@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	common "onepass.app/facility/hts/common"
+	typing "onepass.app/facility/internal/typing"
+)
+
+func TestPermissionCacheGet(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	permissionCache := NewPermissionCache(time.Minute, 2)
+	permissionCache.now = func() time.Time { return now }
+	loads := 0
+	load := func(isAllowed bool) func() (bool, typing.CustomError) {
+		return func() (bool, typing.CustomError) {
+			loads++
+			return isAllowed, nil
+		}
+	}
+
+	key := PermissionKey{UserID: 1, OrganizationID: 2, Permission: common.Permission_UPDATE_FACILITY}
+	isAllowed, err := permissionCache.Get(context.Background(), key, load(true))
+	assert.Nil(err)
+	assert.True(isAllowed)
+	isAllowed, _ = permissionCache.Get(context.Background(), key, load(false))
+	assert.True(isAllowed)
+	assert.Equal(1, loads)
+
+	now = now.Add(time.Minute)
+	isAllowed, _ = permissionCache.Get(context.Background(), key, load(false))
+	assert.False(isAllowed)
+	assert.Equal(2, loads)
+
+	_, err = permissionCache.Get(context.Background(), PermissionKey{UserID: 3}, func() (bool, typing.CustomError) {
+		return false, &typing.GRPCError{Name: "Account service"}
+	})
+	assert.Equal("service error: Account service", err.Error())
+	assert.Equal(PermissionStats{Hits: 1, Misses: 3, Size: 1}, permissionCache.Stats())
+
+	permissionCache.Flush()
+	assert.Equal(0, permissionCache.Stats().Size)
+	var nilCache *PermissionCache
+	isAllowed, _ = nilCache.Get(context.Background(), key, load(true))
+	assert.True(isAllowed)
+}
+
+func TestPermissionCacheEviction(t *testing.T) {
+	assert := assert.New(t)
+
+	permissionCache := NewPermissionCache(time.Minute, 2)
+	load := func() (bool, typing.CustomError) { return true, nil }
+	first := PermissionKey{UserID: 1}
+	second := PermissionKey{UserID: 2}
+	third := PermissionKey{UserID: 3}
+
+	permissionCache.Get(context.Background(), first, load)
+	permissionCache.Get(context.Background(), second, load)
+	// first becomes the most recently used so second is evicted
+	permissionCache.Get(context.Background(), first, load)
+	permissionCache.Get(context.Background(), third, load)
+
+	assert.Equal(PermissionStats{Hits: 1, Misses: 3, Evictions: 1, Size: 2}, permissionCache.Stats())
+	permissionCache.Get(context.Background(), second, load)
+	assert.Equal(int64(4), permissionCache.Stats().Misses)
+}
+
+func TestPermissionCacheCoalescing(t *testing.T) {
+	assert := assert.New(t)
+
+	permissionCache := NewPermissionCache(time.Minute, 10)
+	key := PermissionKey{UserID: 1}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loads := 0
+	load := func() (bool, typing.CustomError) {
+		loads++
+		close(started)
+		<-release
+		return true, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]bool, 5)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0], _ = permissionCache.Get(context.Background(), key, load)
+	}()
+	<-started
+	for i := 1; i < len(results); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = permissionCache.Get(context.Background(), key, load)
+		}(i)
+	}
+	for permissionCache.Stats().Coalesced != int64(len(results)-1) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(1, loads)
+	assert.Equal([]bool{true, true, true, true, true}, results)
+	assert.Equal(1, permissionCache.Stats().Size)
+}
+
+func TestPermissionCacheFlushDuringLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	permissionCache := NewPermissionCache(time.Minute, 10)
+	key := PermissionKey{UserID: 1}
+	isAllowed, _ := permissionCache.Get(context.Background(), key, func() (bool, typing.CustomError) {
+		permissionCache.Flush()
+		return true, nil
+	})
+	assert.True(isAllowed)
+	assert.Equal(0, permissionCache.Stats().Size)
+}
+
+func TestPermissionCacheCancelledCaller(t *testing.T) {
+	assert := assert.New(t)
+
+	permissionCache := NewPermissionCache(time.Minute, 10)
+	key := PermissionKey{UserID: 1}
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := permissionCache.Get(ctx, key, func() (bool, typing.CustomError) {
+		<-release
+		return true, nil
+	})
+	assert.Equal(codes.Canceled, err.Code())
+
+	// the load goes on for other callers and its result is cached
+	close(release)
+	isAllowed, err := permissionCache.Get(context.Background(), key, nil)
+	assert.Nil(err)
+	assert.True(isAllowed)
+}
@@ -0,0 +1,319 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	common "onepass.app/facility/hts/common"
+	facility "onepass.app/facility/hts/facility"
+	model "onepass.app/facility/internal/model"
+	typing "onepass.app/facility/internal/typing"
+)
+
+// calendarFeedTokenBytes is number of random bytes of a calendar feed token
+const calendarFeedTokenBytes = 32
+
+// generateCalendarFeedToken is function to generate secret token of calendar feed url
+func generateCalendarFeedToken() (string, typing.CustomError) {
+	token := make([]byte, calendarFeedTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return "", &typing.DatabaseError{StatusCode: codes.Internal, Err: err}
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// hashCalendarFeedToken is function to hash calendar feed token as it is stored in database
+func hashCalendarFeedToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func convertCalendarFeedModelToProto(data *model.CalendarFeed) *facility.CalendarFeed {
+	return &facility.CalendarFeed{
+		Id:             data.ID,
+		OrganizationId: data.OrganizationID,
+		FacilityId:     data.FacilityID.Int64,
+		CreatedBy:      data.CreatedBy,
+		CreatedAt:      timestamppb.New(data.CreatedAt),
+	}
+}
+
+// CreateCalendarFeed is function to create calendar feed of the facility or every facility of the organization when facilityID is 0, the token is only returned here
+func (dbs *DataService) CreateCalendarFeed(ctx context.Context, organizationID int64, facilityID int64, userID int64) (*facility.CalendarFeed, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	token, err := generateCalendarFeedToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var feed model.CalendarFeed
+	query := `
+	INSERT INTO calendar_feed (organization_id, facility_id, token_hash, created_by) 
+	VALUES (?, ?, ?, ?) 
+	RETURNING id, organization_id, facility_id, token_hash, created_by, created_at, revoked_at`
+	query = dbs.SQL.Rebind(query)
+	queryFacilityID := sql.NullInt64{Int64: facilityID, Valid: facilityID != 0}
+	if err := dbs.SQL.GetContext(ctx, &feed, query, organizationID, queryFacilityID, hashCalendarFeedToken(token), userID); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	result := convertCalendarFeedModelToProto(&feed)
+	result.Token = token
+	return result, nil
+}
+
+// GetCalendarFeed is function to get calendar feed which is not revoked
+func (dbs *DataService) GetCalendarFeed(ctx context.Context, feedID int64) (*facility.CalendarFeed, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	var feed model.CalendarFeed
+	query := `
+	SELECT id, organization_id, facility_id, token_hash, created_by, created_at, revoked_at 
+	FROM calendar_feed 
+	WHERE id = ? AND revoked_at IS NULL`
+	query = dbs.SQL.Rebind(query)
+	if err := dbs.SQL.GetContext(ctx, &feed, query, feedID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &typing.DatabaseError{
+				Err:        &typing.NotFoundError{Name: "CalendarFeed"},
+				StatusCode: codes.NotFound,
+			}
+		}
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	return convertCalendarFeedModelToProto(&feed), nil
+}
+
+// GetCalendarFeedList is function to get calendar feeds of the organization which are not revoked
+func (dbs *DataService) GetCalendarFeedList(ctx context.Context, organizationID int64) ([]*facility.CalendarFeed, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	var feeds []*model.CalendarFeed
+	query := `
+	SELECT id, organization_id, facility_id, token_hash, created_by, created_at, revoked_at 
+	FROM calendar_feed 
+	WHERE organization_id = ? AND revoked_at IS NULL 
+	ORDER BY id`
+	query = dbs.SQL.Rebind(query)
+	if err := dbs.SQL.SelectContext(ctx, &feeds, query, organizationID); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	result := make([]*facility.CalendarFeed, len(feeds))
+	for i, item := range feeds {
+		result[i] = convertCalendarFeedModelToProto(item)
+	}
+
+	return result, nil
+}
+
+// RevokeCalendarFeed is function to revoke calendar feed, its token stops working immediately
+func (dbs *DataService) RevokeCalendarFeed(ctx context.Context, feedID int64) typing.CustomError {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+	UPDATE calendar_feed 
+	SET revoked_at = NOW() 
+	WHERE id = ? AND revoked_at IS NULL`
+	query = dbs.SQL.Rebind(query)
+	result, err := dbs.SQL.ExecContext(ctx, query, feedID)
+	if err != nil {
+		return &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	if count, _ := result.RowsAffected(); count == 0 {
+		return &typing.DatabaseError{
+			Err:        &typing.NotFoundError{Name: "CalendarFeed"},
+			StatusCode: codes.NotFound,
+		}
+	}
+
+	return nil
+}
+
+// GetCalendarFeedFacilities is function to get the calendar feed of the token which is not revoked and facilities it covers
+func (dbs *DataService) GetCalendarFeedFacilities(ctx context.Context, token string) (*facility.CalendarFeed, []*common.Facility, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	feed := model.CalendarFeed{}
+	query := `
+	SELECT id, organization_id, facility_id, token_hash, created_by, created_at, revoked_at 
+	FROM calendar_feed 
+	WHERE token_hash = ? AND revoked_at IS NULL`
+	query = dbs.SQL.Rebind(query)
+	if err := dbs.SQL.GetContext(ctx, &feed, query, hashCalendarFeedToken(token)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, &typing.DatabaseError{
+				Err:        &typing.NotFoundError{Name: "CalendarFeed"},
+				StatusCode: codes.NotFound,
+			}
+		}
+		return nil, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	var facilities []*model.Facility
+	query = `
+	SELECT * 
+	FROM facility 
+	WHERE organization_id = ? AND (CAST(? AS bigint) IS NULL OR id = ?) 
+	ORDER BY id`
+	query = dbs.SQL.Rebind(query)
+	if err := dbs.SQL.SelectContext(ctx, &facilities, query, feed.OrganizationID, feed.FacilityID, feed.FacilityID); err != nil {
+		return nil, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	result := make([]*common.Facility, len(facilities))
+	for i, item := range facilities {
+		value, err := dbs.Helper.convertFacilityModelToProto(item)
+		if err != nil {
+			return nil, nil, err
+		}
+		result[i] = value
+	}
+
+	return convertCalendarFeedModelToProto(&feed), result, nil
+}
+
+// ImportBusyBlocks is function to replace busy blocks of the facility imported from the source in one transaction
+func (dbs *DataService) ImportBusyBlocks(ctx context.Context, facilityID int64, source string, blocks []*common.BusyBlock) ([]*common.BusyBlock, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	if strings.TrimSpace(source) == "" {
+		return nil, &typing.InputError{Name: "Source must not be empty"}
+	}
+
+	tx, err := dbs.SQL.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// the facility row is locked like approval so a request can not be approved into a block being imported
+	var id int64
+	query := tx.Rebind(`
+	SELECT id 
+	FROM facility 
+	WHERE id = ? 
+	FOR UPDATE`)
+	err = tx.GetContext(ctx, &id, query, facilityID)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, &typing.DatabaseError{
+			Err:        &typing.NotFoundError{Name: "Facility"},
+			StatusCode: codes.NotFound,
+		}
+	case err != nil:
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	query = tx.Rebind(`
+	DELETE FROM facility_busy_block 
+	WHERE facility_id = ? AND source = ?`)
+	if _, err := tx.ExecContext(ctx, query, facilityID, source); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	layoutTime := "2006-01-02 15:04:05"
+	query = tx.Rebind(`
+	INSERT INTO facility_busy_block (facility_id, source, uid, summary, start, finish) 
+	VALUES (?, ?, ?, ?, ?, ?) 
+	RETURNING *`)
+	result := make([]*common.BusyBlock, len(blocks))
+	for i, block := range blocks {
+		startTime, _ := ptypes.Timestamp(block.Start)
+		finishTime, _ := ptypes.Timestamp(block.Finish)
+		busyBlock := model.BusyBlock{}
+		if err := tx.GetContext(ctx, &busyBlock, query, facilityID, source, block.Uid, block.Summary, startTime.Format(layoutTime), finishTime.Format(layoutTime)); err != nil {
+			return nil, &typing.DatabaseError{
+				Err:        err,
+				StatusCode: codes.Internal,
+			}
+		}
+		result[i] = convertBusyBlockModelToProto(&busyBlock)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	return result, nil
+}
+
+// GetBusyBlocks is function to get busy blocks of the facility overlapping start to finish, earliest first
+func (dbs *DataService) GetBusyBlocks(ctx context.Context, facilityID int64, start time.Time, finish time.Time) ([]*common.BusyBlock, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	var busyBlocks []*model.BusyBlock
+	query := `
+	SELECT * 
+	FROM facility_busy_block 
+	WHERE facility_id = ? 
+	AND start < ? AND finish > ? 
+	ORDER BY start, id`
+	query = dbs.SQL.Rebind(query)
+
+	layoutTime := "2006-01-02 15:04:05"
+	if err := dbs.SQL.SelectContext(ctx, &busyBlocks, query, facilityID, finish.UTC().Format(layoutTime), start.UTC().Format(layoutTime)); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	result := make([]*common.BusyBlock, len(busyBlocks))
+	for i, item := range busyBlocks {
+		result[i] = convertBusyBlockModelToProto(item)
+	}
+
+	return result, nil
+}
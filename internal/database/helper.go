@@ -1,11 +1,17 @@
 package database
 
 import (
+	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
@@ -35,6 +41,136 @@ func ConvertOperatingHoursModelToProto(operatingHours types.JSONText) ([]*common
 	return result, nil
 }
 
+// convertOperatingHoursProtoToModel is fuction to convert operationHours proto to JSON for database
+func convertOperatingHoursProtoToModel(operatingHours []*common.OperatingHour) (types.JSONText, typing.CustomError) {
+	message := make([]*model.OperatingHour, len(operatingHours))
+	for i, operatingHour := range operatingHours {
+		message[i] = &model.OperatingHour{
+			Day:        operatingHour.Day.String(),
+			StartHour:  operatingHour.StartHour,
+			FinishHour: operatingHour.FinishHour,
+		}
+	}
+
+	result, err := json.Marshal(message)
+	if err != nil {
+		return nil, &typing.DatabaseError{StatusCode: codes.Internal, Err: err}
+	}
+	return result, nil
+}
+
+// convertFacilityAttributesModelToProto is fuction to convert attributes JSON from database to proto, empty JSON means no attributes
+func convertFacilityAttributesModelToProto(attributes types.JSONText) (*common.FacilityAttributes, typing.CustomError) {
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+
+	var message model.FacilityAttributes
+	if err := json.Unmarshal(attributes, &message); err != nil {
+		return nil, &typing.DatabaseError{StatusCode: codes.DataLoss, Err: err}
+	}
+
+	return &common.FacilityAttributes{
+		IsOutdoor:              message.IsOutdoor,
+		HasProjector:           message.HasProjector,
+		IsWheelchairAccessible: message.IsWheelchairAccessible,
+	}, nil
+}
+
+// convertFacilityAttributesProtoToModel is fuction to convert attributes proto to JSON for database
+func convertFacilityAttributesProtoToModel(attributes *common.FacilityAttributes) (types.JSONText, typing.CustomError) {
+	result, err := json.Marshal(&model.FacilityAttributes{
+		IsOutdoor:              attributes.GetIsOutdoor(),
+		HasProjector:           attributes.GetHasProjector(),
+		IsWheelchairAccessible: attributes.GetIsWheelchairAccessible(),
+	})
+	if err != nil {
+		return nil, &typing.DatabaseError{StatusCode: codes.Internal, Err: err}
+	}
+	return result, nil
+}
+
+// convertFacilityQuotaModelToProto is fuction to convert quota JSON from database to proto
+func convertFacilityQuotaModelToProto(quota types.JSONText) (*common.FacilityQuota, typing.CustomError) {
+	if len(quota) == 0 {
+		return nil, nil
+	}
+
+	var message model.FacilityQuota
+	if err := json.Unmarshal(quota, &message); err != nil {
+		return nil, &typing.DatabaseError{StatusCode: codes.DataLoss, Err: err}
+	}
+
+	return &common.FacilityQuota{
+		MaxHoursPerWeek:    message.MaxHoursPerWeek,
+		MaxPendingRequests: message.MaxPendingRequests,
+	}, nil
+}
+
+// convertFacilityQuotaProtoToModel is fuction to convert quota proto to JSON for database
+func convertFacilityQuotaProtoToModel(quota *common.FacilityQuota) (types.JSONText, typing.CustomError) {
+	result, err := json.Marshal(&model.FacilityQuota{
+		MaxHoursPerWeek:    quota.GetMaxHoursPerWeek(),
+		MaxPendingRequests: quota.GetMaxPendingRequests(),
+	})
+	if err != nil {
+		return nil, &typing.DatabaseError{StatusCode: codes.Internal, Err: err}
+	}
+	return result, nil
+}
+
+// convertApprovalChainModelToProto is fuction to convert approvalChain JSON from database to proto, a chain without stages is nil
+func convertApprovalChainModelToProto(approvalChain types.JSONText) (*common.ApprovalChain, typing.CustomError) {
+	if len(approvalChain) == 0 {
+		return nil, nil
+	}
+
+	var message *model.ApprovalChain
+	if err := json.Unmarshal(approvalChain, &message); err != nil {
+		return nil, &typing.DatabaseError{StatusCode: codes.DataLoss, Err: err}
+	}
+	if message == nil || len(message.Stages) == 0 {
+		return nil, nil
+	}
+
+	stages := make([]*common.ApprovalStage, len(message.Stages))
+	for i, stage := range message.Stages {
+		stages[i] = &common.ApprovalStage{
+			Name:        stage.Name,
+			ApproverIds: stage.ApproverIDs,
+		}
+	}
+	return &common.ApprovalChain{
+		Mode:   common.ApprovalMode(common.ApprovalMode_value[message.Mode]),
+		Stages: stages,
+		Quorum: message.Quorum,
+	}, nil
+}
+
+// convertApprovalChainProtoToModel is fuction to convert approvalChain proto to JSON for database, a chain without stages is stored as null
+func convertApprovalChainProtoToModel(approvalChain *common.ApprovalChain) (types.JSONText, typing.CustomError) {
+	var message *model.ApprovalChain
+	if len(approvalChain.GetStages()) != 0 {
+		message = &model.ApprovalChain{
+			Mode:   approvalChain.Mode.String(),
+			Stages: make([]*model.ApprovalStage, len(approvalChain.Stages)),
+			Quorum: approvalChain.Quorum,
+		}
+		for i, stage := range approvalChain.Stages {
+			message.Stages[i] = &model.ApprovalStage{
+				Name:        stage.Name,
+				ApproverIDs: stage.ApproverIds,
+			}
+		}
+	}
+
+	result, err := json.Marshal(message)
+	if err != nil {
+		return nil, &typing.DatabaseError{StatusCode: codes.Internal, Err: err}
+	}
+	return result, nil
+}
+
 // OperatingHoursModelToProto type of function to inject to helper
 type OperatingHoursModelToProto func(operatingHours types.JSONText) ([]*common.OperatingHour, typing.CustomError)
 
@@ -50,6 +186,26 @@ func (dbHelper *Helper) convertFacilityModelToProto(data *model.Facility) (*comm
 		return nil, err
 	}
 
+	attributes, err := convertFacilityAttributesModelToProto(data.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	approvalChain, err := convertApprovalChainModelToProto(data.ApprovalChain)
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := convertFacilityQuotaModelToProto(data.Quota)
+	if err != nil {
+		return nil, err
+	}
+
+	bookingWindow, err := convertBookingWindowModelToProto(data.BookingWindow)
+	if err != nil {
+		return nil, err
+	}
+
 	return &common.Facility{
 		Id:             data.ID,
 		OrganizationId: data.OrganizationID,
@@ -58,6 +214,13 @@ func (dbHelper *Helper) convertFacilityModelToProto(data *model.Facility) (*comm
 		Longitude:      data.Longitude,
 		OperatingHours: OperatingHours,
 		Description:    data.Description,
+		SlotMinutes:    data.SlotMinutes,
+		TimeZone:       data.TimeZone,
+		Capacity:       data.Capacity,
+		Attributes:     attributes,
+		ApprovalChain:  approvalChain,
+		Quota:          quota,
+		BookingWindow:  bookingWindow,
 	}, nil
 }
 
@@ -66,15 +229,33 @@ func (dbHelper *Helper) convertFacilityRequestModelToProto(data *model.FacilityR
 	if data.RejectReason.Valid {
 		rejectReason = &wrappers.StringValue{Value: data.RejectReason.String}
 	}
+	cancelledBy, cancelledAt := convertCancellationModelToProto(data.CancelledBy, data.CancelledAt)
 	return &common.FacilityRequest{
-		Id:           data.ID,
-		EventId:      data.EventID,
-		FacilityId:   data.FacilityID,
-		Status:       common.Status(common.Status_value[data.Status]),
-		RejectReason: rejectReason,
-		Start:        timestamppb.New(data.Start),
-		Finish:       timestamppb.New(data.Finish),
+		Id:                      data.ID,
+		EventId:                 data.EventID,
+		FacilityId:              data.FacilityID,
+		Status:                  common.Status(common.Status_value[data.Status]),
+		RejectReason:            rejectReason,
+		Start:                   timestamppb.New(data.Start),
+		Finish:                  timestamppb.New(data.Finish),
+		CancelledBy:             cancelledBy,
+		CancelledAt:             cancelledAt,
+		SeriesId:                data.SeriesID.Int64,
+		CreatedAt:               timestamppb.New(data.CreatedAt),
+		RequesterOrganizationId: data.RequesterOrganizationID,
+	}
+}
+
+func convertCancellationModelToProto(cancelledBy sql.NullInt64, cancelledAt sql.NullTime) (*wrappers.Int64Value, *timestamppb.Timestamp) {
+	var user *wrappers.Int64Value
+	if cancelledBy.Valid {
+		user = &wrappers.Int64Value{Value: cancelledBy.Int64}
 	}
+	var at *timestamppb.Timestamp
+	if cancelledAt.Valid {
+		at = timestamppb.New(cancelledAt.Time)
+	}
+	return user, at
 }
 
 func (dbHelper *Helper) convertFacilityRequestWithInfoModelToProto(data *model.FacilityRequestWithInfo) (*facility.FacilityRequestWithFacilityInfo, typing.CustomError) {
@@ -88,64 +269,523 @@ func (dbHelper *Helper) convertFacilityRequestWithInfoModelToProto(data *model.F
 		return nil, err
 	}
 
+	attributes, err := convertFacilityAttributesModelToProto(data.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelledBy, cancelledAt := convertCancellationModelToProto(data.CancelledBy, data.CancelledAt)
 	return &facility.FacilityRequestWithFacilityInfo{
-		Id:             data.ID,
-		EventId:        data.EventID,
-		FacilityId:     data.FacilityID,
-		Status:         common.Status(common.Status_value[data.Status]),
-		RejectReason:   rejectReason,
-		Start:          timestamppb.New(data.Start),
-		Finish:         timestamppb.New(data.Finish),
-		OrganizationId: data.OrganizationID,
-		FacilityName:   data.FacilityName,
-		Latitude:       data.Latitude,
-		Longitude:      data.Longitude,
-		OperatingHours: OperatingHours,
-		Description:    data.Description,
+		Id:                      data.ID,
+		EventId:                 data.EventID,
+		FacilityId:              data.FacilityID,
+		Status:                  common.Status(common.Status_value[data.Status]),
+		RejectReason:            rejectReason,
+		Start:                   timestamppb.New(data.Start),
+		Finish:                  timestamppb.New(data.Finish),
+		CancelledBy:             cancelledBy,
+		CancelledAt:             cancelledAt,
+		SeriesId:                data.SeriesID.Int64,
+		CreatedAt:               timestamppb.New(data.CreatedAt),
+		OrganizationId:          data.OrganizationID,
+		FacilityName:            data.FacilityName,
+		Latitude:                data.Latitude,
+		Longitude:               data.Longitude,
+		OperatingHours:          OperatingHours,
+		Description:             data.Description,
+		SlotMinutes:             data.SlotMinutes,
+		TimeZone:                data.TimeZone,
+		Capacity:                data.Capacity,
+		Attributes:              attributes,
+		RequesterOrganizationId: data.RequesterOrganizationID,
 	}, nil
 }
 
-func (dbHelper *Helper) checkDateInput(start time.Time, finish time.Time, operatingHours []*common.OperatingHour) typing.CustomError {
-	if dbHelper.DayDifference(start, finish) != 0 {
-		return &typing.InputError{Name: "Start and Finish must be the same day"}
+// checkBookingWindow is function to validate booking window, its lead time must leave bookable days
+func checkBookingWindow(window *common.BookingWindow) typing.CustomError {
+	if window.GetMaxAdvanceDays() < 0 || window.GetMinLeadMinutes() < 0 {
+		return &typing.InputError{Name: "BookingWindow must not be negative"}
+	}
+	if int(window.GetMinLeadMinutes()) >= helper.MaxAdvanceDaysOrDefault(window)*24*60 {
+		return &typing.InputError{Name: "MinLeadMinutes must be shorter than MaxAdvanceDays"}
 	}
+	return nil
+}
 
-	now := time.Now()
-	dayDifferenceFromNow := dbHelper.DayDifference(now, start)
-	if dayDifferenceFromNow >= 30 {
-		return &typing.InputError{Name: "Booking date can only be within 30 days period from today"}
+// mergeBookingWindow is function to fill fields facility's booking window leaves unset with organization's default,
+// fields unset in both stay zero so the built-in default applies
+func mergeBookingWindow(facilityWindow *common.BookingWindow, organizationWindow *common.BookingWindow) *common.BookingWindow {
+	result := &common.BookingWindow{
+		MaxAdvanceDays: facilityWindow.GetMaxAdvanceDays(),
+		MinLeadMinutes: facilityWindow.GetMinLeadMinutes(),
+	}
+	if result.MaxAdvanceDays == 0 {
+		result.MaxAdvanceDays = organizationWindow.GetMaxAdvanceDays()
 	}
+	if result.MinLeadMinutes == 0 {
+		result.MinLeadMinutes = organizationWindow.GetMinLeadMinutes()
+	}
+	return result
+}
 
-	HourStart, MinuteStart, secondStart := start.Clock()
-	HourFinish, MinuteFinish, secondFinish := finish.Clock()
+// convertBookingWindowModelToProto is fuction to convert booking window JSON from database to proto
+func convertBookingWindowModelToProto(window types.JSONText) (*common.BookingWindow, typing.CustomError) {
+	if len(window) == 0 {
+		return nil, nil
+	}
 
-	if dayDifferenceFromNow < 0 || (dayDifferenceFromNow == 0 && HourStart < now.Hour()) {
-		return &typing.InputError{Name: "Booking time must not be in the past"}
+	var message *model.BookingWindow
+	if err := json.Unmarshal(window, &message); err != nil {
+		return nil, &typing.DatabaseError{StatusCode: codes.DataLoss, Err: err}
+	}
+	if message == nil {
+		return nil, nil
+	}
+
+	return &common.BookingWindow{
+		MaxAdvanceDays: message.MaxAdvanceDays,
+		MinLeadMinutes: message.MinLeadMinutes,
+	}, nil
+}
+
+// convertBookingWindowProtoToModel is fuction to convert booking window proto to JSON for database, null means organization's default
+func convertBookingWindowProtoToModel(window *common.BookingWindow) (types.JSONText, typing.CustomError) {
+	if window == nil {
+		return types.JSONText("null"), nil
+	}
+
+	result, err := json.Marshal(&model.BookingWindow{
+		MaxAdvanceDays: window.MaxAdvanceDays,
+		MinLeadMinutes: window.MinLeadMinutes,
+	})
+	if err != nil {
+		return nil, &typing.DatabaseError{StatusCode: codes.Internal, Err: err}
+	}
+	return result, nil
+}
+
+// checkApprovalChain is function to validate approval chain of facility, a chain without stages is not used
+func checkApprovalChain(chain *common.ApprovalChain) typing.CustomError {
+	if len(chain.GetStages()) == 0 {
+		return nil
+	}
+
+	if _, ok := common.ApprovalMode_name[int32(chain.Mode)]; !ok {
+		return &typing.InputError{Name: "Unknown mode of approvalChain"}
+	}
+	if chain.Mode == common.ApprovalMode_QUORUM && (chain.Quorum < 1 || int(chain.Quorum) > len(chain.Stages)) {
+		return &typing.InputError{Name: "Quorum must be between 1 and number of stages in approvalChain"}
+	}
+
+	for _, stage := range chain.Stages {
+		if len(stage.ApproverIds) == 0 {
+			return &typing.InputError{Name: "Every stage in approvalChain must have an approver"}
+		}
+	}
+
+	return nil
+}
+
+// nextApprovalStage is function to get index of the stage the approver decides for, an ordered chain only accepts its first stage not approved yet
+func nextApprovalStage(chain *common.ApprovalChain, decisions []*model.ApprovalDecision, approverID int64) (int32, typing.CustomError) {
+	if helper.ApproverStage(chain, approverID) < 0 {
+		return 0, &typing.DatabaseError{
+			Err:        &typing.InputError{Name: "User is not an approver of the facility"},
+			StatusCode: codes.PermissionDenied,
+		}
+	}
+
+	approvedStages := map[int32]bool{}
+	for _, decision := range decisions {
+		if decision.ApproverID == approverID {
+			return 0, &typing.DatabaseError{
+				Err:        &typing.InputError{Name: "Approver has already decided"},
+				StatusCode: codes.FailedPrecondition,
+			}
+		}
+		approvedStages[decision.Stage] = true
+	}
+
+	for i, stage := range chain.Stages {
+		index := int32(i)
+		switch {
+		case approvedStages[index]:
+			continue
+		case helper.IsStageApprover(stage, approverID):
+			return index, nil
+		case chain.Mode == common.ApprovalMode_ORDERED:
+			return 0, &typing.DatabaseError{
+				Err:        &typing.InputError{Name: "Waiting for approval of stage " + stage.Name},
+				StatusCode: codes.FailedPrecondition,
+			}
+		}
 	}
-	if (MinuteStart + secondStart + MinuteFinish + secondFinish) != 0 {
-		return &typing.InputError{Name: "Minutes and seconds must be 0"}
+
+	return 0, &typing.DatabaseError{
+		Err:        &typing.InputError{Name: "Stages of the approver are already approved"},
+		StatusCode: codes.FailedPrecondition,
+	}
+}
+
+// isApprovalChainSatisfied is function to check if approvals are enough for the request to be approved
+func isApprovalChainSatisfied(chain *common.ApprovalChain, decisions []*model.ApprovalDecision) bool {
+	approvedStages := map[int32]bool{}
+	for _, decision := range decisions {
+		if decision.Decision == common.Status_APPROVED.String() {
+			approvedStages[decision.Stage] = true
+		}
+	}
+
+	if chain.Mode == common.ApprovalMode_QUORUM {
+		return len(approvedStages) >= int(chain.Quorum)
+	}
+	return len(approvedStages) == len(chain.Stages)
+}
+
+func convertApprovalDecisionModelToProto(data *model.ApprovalDecision) *facility.ApprovalDecision {
+	var reason *wrappers.StringValue
+	if data.Reason.Valid {
+		reason = &wrappers.StringValue{Value: data.Reason.String}
+	}
+	return &facility.ApprovalDecision{
+		FacilityRequestId: data.FacilityRequestID,
+		Stage:             data.Stage,
+		ApproverId:        data.ApproverID,
+		Decision:          common.Status(common.Status_value[data.Decision]),
+		Reason:            reason,
+		CreatedAt:         timestamppb.New(data.CreatedAt),
+	}
+}
+
+// facilityRequestTransitions is statuses a facility request can be changed from to each status, rejected and cancelled requests are final
+var facilityRequestTransitions = map[common.Status][]common.Status{
+	common.Status_PENDING:   {common.Status_WAITLISTED},
+	common.Status_APPROVED:  {common.Status_PENDING},
+	common.Status_REJECTED:  {common.Status_PENDING, common.Status_WAITLISTED},
+	common.Status_CANCELLED: {common.Status_PENDING, common.Status_APPROVED, common.Status_WAITLISTED},
+}
+
+// selectWaitlistPromotions is function to pick waitlisted requests in order which do not overlap any picked before,
+// so requests waiting for different parts of the freed time are all promoted
+func selectWaitlistPromotions(candidates []*model.FacilityRequest) []*model.FacilityRequest {
+	var result []*model.FacilityRequest
+	for _, candidate := range candidates {
+		isOverlap := false
+		for _, picked := range result {
+			if picked.Start.Before(candidate.Finish) && picked.Finish.After(candidate.Start) {
+				isOverlap = true
+				break
+			}
+		}
+		if !isOverlap {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+// allowedPreviousStatuses is function to get statuses of facilityRequestTransitions as they are stored in database
+func allowedPreviousStatuses(status common.Status) []string {
+	result := make([]string, len(facilityRequestTransitions[status]))
+	for i, previous := range facilityRequestTransitions[status] {
+		result[i] = previous.String()
+	}
+	return result
+}
+
+func convertFacilityRequestHistoryModelToProto(data *model.FacilityRequestHistory) *facility.FacilityRequestHistory {
+	var reason *wrappers.StringValue
+	if data.Reason.Valid {
+		reason = &wrappers.StringValue{Value: data.Reason.String}
 	}
+	return &facility.FacilityRequestHistory{
+		Id:                data.ID,
+		FacilityRequestId: data.FacilityRequestID,
+		ActorId:           data.ActorID,
+		OldStatus:         common.Status(common.Status_value[data.OldStatus]),
+		NewStatus:         common.Status(common.Status_value[data.NewStatus]),
+		Reason:            reason,
+		CreatedAt:         timestamppb.New(data.CreatedAt),
+	}
+}
+
+func (dbHelper *Helper) checkDateInput(start time.Time, finish time.Time, facility *common.Facility, window *common.BookingWindow, overrides map[string]*common.OperatingHourOverride) typing.CustomError {
+	location, err := time.LoadLocation(facility.TimeZone)
+	if err != nil {
+		return &typing.DatabaseError{StatusCode: codes.DataLoss, Err: err}
+	}
+
+	// day boundaries and operating hours are in the facility's time zone
+	start = start.In(location)
+	finish = finish.In(location)
+	now := time.Now().In(location)
 
-	if HourStart > HourFinish {
+	if !start.Before(finish) {
 		return &typing.InputError{Name: "Start must be earlier than Finish"}
 	}
 
-	weekDayStart := start.Weekday()
-	var operatingHour *common.OperatingHour
-	for _, value := range operatingHours {
-		day := int(value.Day.Number())
-		if day == int(weekDayStart) {
-			operatingHour = value
+	if err := helper.CheckAdvanceWindow(now, finish, window); err != nil {
+		return err
+	}
+
+	dayDifferenceFromNow := dbHelper.DayDifference(now, start)
+	slot := time.Duration(helper.SlotMinutesOrDefault(facility.SlotMinutes)) * time.Minute
+	currentSlot := now.Add(-(helper.TimeOfDay(now) % slot))
+	if dayDifferenceFromNow < 0 || (dayDifferenceFromNow == 0 && start.Before(currentSlot)) {
+		return &typing.InputError{Name: "Booking time must not be in the past"}
+	}
+	if err := helper.CheckLeadTime(now, start, window); err != nil {
+		return err
+	}
+	if helper.TimeOfDay(start)%slot != 0 || helper.TimeOfDay(finish)%slot != 0 {
+		return &typing.InputError{Name: fmt.Sprintf("Start and Finish must be aligned to %d minutes slot", slot/time.Minute)}
+	}
+
+	// every day covered by the booking must be open, the first day from start and the last day until finish
+	lastDay := dbHelper.DayDifference(start, finish)
+	for i := 0; i <= lastDay; i++ {
+		day := start.AddDate(0, 0, i)
+		operatingHour := helper.OperatingHourOfDay(day, facility.OperatingHours, overrides)
+		if operatingHour == nil {
+			return &typing.InputError{Name: "Not in operatingHours on " + day.Format("2006-01-02")}
+		}
+
+		opening := time.Duration(operatingHour.StartHour) * time.Hour
+		closing := time.Duration(operatingHour.FinishHour) * time.Hour
+		isStartAfterOpening := i != 0 || opening <= helper.TimeOfDay(start)
+		isFinishBeforeClose := i != lastDay || helper.TimeOfDay(finish) <= closing
+		if !isStartAfterOpening || !isFinishBeforeClose {
+			return &typing.InputError{Name: "Not in operatingHours on " + day.Format("2006-01-02")}
 		}
 	}
-	if operatingHour == nil {
-		return &typing.InputError{Name: "Not in operatingHours"}
+
+	return nil
+}
+
+func (dbHelper *Helper) checkFacilityInput(data *common.Facility) typing.CustomError {
+	if strings.TrimSpace(data.Name) == "" {
+		return &typing.InputError{Name: "Name must not be empty"}
+	}
+
+	if err := checkCoordinate(data.Latitude, data.Longitude); err != nil {
+		return err
+	}
+
+	if _, err := time.LoadLocation(data.TimeZone); err != nil || data.TimeZone == "Local" {
+		return &typing.InputError{Name: "TimeZone must be an IANA time zone"}
 	}
 
-	isStartAfterOpening := int(operatingHour.StartHour) <= HourStart
-	isFinishBeforeClose := HourFinish <= int(operatingHour.FinishHour)
-	if !isStartAfterOpening || !isFinishBeforeClose {
-		return &typing.InputError{Name: "Not in operatingHours"}
+	if data.SlotMinutes < 0 || (data.SlotMinutes > 0 && 60%data.SlotMinutes != 0) {
+		return &typing.InputError{Name: "SlotMinutes must be a divisor of 60"}
+	}
+
+	if data.Capacity < 0 {
+		return &typing.InputError{Name: "Capacity must not be negative"}
+	}
+
+	if data.Quota.GetMaxHoursPerWeek() < 0 || data.Quota.GetMaxPendingRequests() < 0 {
+		return &typing.InputError{Name: "Quota must not be negative"}
+	}
+
+	if err := checkBookingWindow(data.BookingWindow); err != nil {
+		return err
+	}
+
+	if err := checkApprovalChain(data.ApprovalChain); err != nil {
+		return err
+	}
+
+	days := map[common.DayOfWeek]bool{}
+	for _, operatingHour := range data.OperatingHours {
+		if _, ok := common.DayOfWeek_name[int32(operatingHour.Day)]; !ok {
+			return &typing.InputError{Name: "Unknown day in operatingHours"}
+		}
+		if days[operatingHour.Day] {
+			return &typing.InputError{Name: "Duplicate day in operatingHours"}
+		}
+		days[operatingHour.Day] = true
+
+		if operatingHour.StartHour < 0 || operatingHour.FinishHour > 24 {
+			return &typing.InputError{Name: "Hours in operatingHours must be between 0 and 24"}
+		}
+		if operatingHour.StartHour >= operatingHour.FinishHour {
+			return &typing.InputError{Name: "StartHour must be earlier than FinishHour in operatingHours"}
+		}
+	}
+
+	return nil
+}
+
+const (
+	defaultPageSize  = 50
+	maxPageSize      = 100
+	cursorLayoutTime = "2006-01-02 15:04:05.999999"
+)
+
+// pageCursor is position of the last item of a page, it is encoded as page token
+type pageCursor struct {
+	SortBy     facility.SortBy `json:"sort_by,omitempty"`
+	Descending bool            `json:"descending,omitempty"`
+	Value      time.Time       `json:"value,omitempty"`
+	ID         int64           `json:"id"`
+}
+
+func pageSizeOrDefault(pageSize int32) int {
+	switch {
+	case pageSize <= 0:
+		return defaultPageSize
+	case pageSize > maxPageSize:
+		return maxPageSize
+	default:
+		return int(pageSize)
+	}
+}
+
+func encodePageToken(cursor *pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(token string) (*pageCursor, typing.CustomError) {
+	var cursor pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, &typing.InputError{Name: "Invalid page token"}
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, &typing.InputError{Name: "Invalid page token"}
+	}
+	return &cursor, nil
+}
+
+// buildFacilityRequestListCondition is function to build WHERE, ORDER BY and LIMIT of facility request list from its option
+func buildFacilityRequestListCondition(conditions []string, params []interface{}, option *facility.FacilityRequestListOption) (string, []interface{}, typing.CustomError) {
+	if len(option.GetStatus()) != 0 {
+		status := make([]string, len(option.Status))
+		for i, item := range option.Status {
+			status[i] = item.String()
+		}
+		conditions = append(conditions, "r.status = ANY(?)")
+		params = append(params, pq.Array(status))
+	}
+	if option.GetFacilityId() != 0 {
+		conditions = append(conditions, "r.facility_id = ?")
+		params = append(params, option.FacilityId)
+	}
+	if option.GetEventId() != 0 {
+		conditions = append(conditions, "r.event_id = ?")
+		params = append(params, option.EventId)
+	}
+	if option.GetStart() != nil {
+		conditions = append(conditions, "r.finish > ?")
+		params = append(params, helper.TimeStampToText(option.Start, cursorLayoutTime))
+	}
+	if option.GetEnd() != nil {
+		conditions = append(conditions, "r.start < ?")
+		params = append(params, helper.TimeStampToText(option.End, cursorLayoutTime))
+	}
+
+	column := "r.start"
+	if option.GetSortBy() == facility.SortBy_CREATED_AT {
+		column = "r.created_at"
+	}
+	comparator, order := ">", "ASC"
+	if option.GetDescending() {
+		comparator, order = "<", "DESC"
+	}
+
+	if option.GetPageToken() != "" {
+		cursor, err := decodePageToken(option.PageToken)
+		if err != nil {
+			return "", nil, err
+		}
+		if cursor.SortBy != option.GetSortBy() || cursor.Descending != option.GetDescending() {
+			return "", nil, &typing.InputError{Name: "Page token does not match sorting"}
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, r.id) %s (?, ?)", column, comparator))
+		params = append(params, cursor.Value.UTC().Format(cursorLayoutTime), cursor.ID)
+	}
+
+	var condition string
+	if len(conditions) != 0 {
+		condition = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	condition += fmt.Sprintf(" ORDER BY %s %s, r.id %s LIMIT ?", column, order, order)
+	params = append(params, pageSizeOrDefault(option.GetPageSize())+1)
+
+	return condition, params, nil
+}
+
+func checkCoordinate(latitude float64, longitude float64) typing.CustomError {
+	if latitude < -90 || latitude > 90 {
+		return &typing.InputError{Name: "Latitude must be between -90 and 90"}
+	}
+
+	if longitude < -180 || longitude > 180 {
+		return &typing.InputError{Name: "Longitude must be between -180 and 180"}
+	}
+
+	return nil
+}
+
+// earthRadiusMeters is mean radius of the earth used for great-circle distance
+const earthRadiusMeters = 6371000
+
+// boundingBox is function to get latitude and longitude range containing every point within radius, longitude range is nil when it wraps around a pole or the antimeridian
+func boundingBox(latitude float64, longitude float64, radiusMeters float64) ([2]float64, *[2]float64) {
+	angularRadius := radiusMeters / earthRadiusMeters
+	latitudeDelta := angularRadius * 180 / math.Pi
+	latitudeRange := [2]float64{latitude - latitudeDelta, latitude + latitudeDelta}
+	if latitudeRange[0] <= -90 || latitudeRange[1] >= 90 {
+		return latitudeRange, nil
+	}
+
+	longitudeDelta := math.Asin(math.Sin(angularRadius)/math.Cos(latitude*math.Pi/180)) * 180 / math.Pi
+	longitudeRange := [2]float64{longitude - longitudeDelta, longitude + longitudeDelta}
+	if longitudeRange[0] < -180 || longitudeRange[1] > 180 {
+		return latitudeRange, nil
+	}
+
+	return latitudeRange, &longitudeRange
+}
+
+func convertOperatingHourOverrideModelToProto(data *model.OperatingHourOverride) *common.OperatingHourOverride {
+	return &common.OperatingHourOverride{
+		FacilityId: data.FacilityID,
+		Date:       data.Date.Format(helper.DateLayout),
+		IsClosed:   data.IsClosed,
+		StartHour:  data.StartHour,
+		FinishHour: data.FinishHour,
+		Reason:     data.Reason,
+	}
+}
+
+func convertBusyBlockModelToProto(data *model.BusyBlock) *common.BusyBlock {
+	return &common.BusyBlock{
+		Id:         data.ID,
+		FacilityId: data.FacilityID,
+		Source:     data.Source,
+		Uid:        data.UID,
+		Summary:    data.Summary,
+		Start:      timestamppb.New(data.Start),
+		Finish:     timestamppb.New(data.Finish),
+	}
+}
+
+func checkOperatingHourOverrideInput(data *common.OperatingHourOverride) typing.CustomError {
+	if _, err := time.Parse(helper.DateLayout, data.Date); err != nil {
+		return &typing.InputError{Name: "Date must be in YYYY-MM-DD format"}
+	}
+
+	if data.IsClosed {
+		return nil
+	}
+
+	if data.StartHour < 0 || data.FinishHour > 24 {
+		return &typing.InputError{Name: "Hours must be between 0 and 24"}
+	}
+	if data.StartHour >= data.FinishHour {
+		return &typing.InputError{Name: "StartHour must be earlier than FinishHour"}
 	}
 
 	return nil
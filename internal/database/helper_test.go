@@ -1,14 +1,21 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/jmoiron/sqlx/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	common "onepass.app/facility/hts/common"
+	facility "onepass.app/facility/hts/facility"
+	helperPkg "onepass.app/facility/internal/helper"
 	model "onepass.app/facility/internal/model"
 	typing "onepass.app/facility/internal/typing"
 )
@@ -131,3 +138,519 @@ func TestConvertFacilityModelToProtoEmpty(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(&expected, protoFacility)
 }
+
+func TestFacilityAttributes(t *testing.T) {
+	assert := assert.New(t)
+
+	attributes, err := convertFacilityAttributesModelToProto(nil)
+	assert.Nil(err)
+	assert.Nil(attributes)
+
+	expected := &common.FacilityAttributes{IsOutdoor: true, IsWheelchairAccessible: true}
+	data, err := convertFacilityAttributesProtoToModel(expected)
+	assert.Nil(err)
+	attributes, err = convertFacilityAttributesModelToProto(data)
+	assert.Nil(err)
+	assert.True(proto.Equal(expected, attributes))
+
+	_, err = convertFacilityAttributesModelToProto(types.JSONText(`{"is_outdoor": yes}`))
+	assert.NotNil(err)
+	assert.Equal(codes.DataLoss, err.Code())
+}
+
+func TestFacilityQuota(t *testing.T) {
+	assert := assert.New(t)
+
+	quota, err := convertFacilityQuotaModelToProto(nil)
+	assert.Nil(err)
+	assert.Nil(quota)
+
+	expected := &common.FacilityQuota{MaxHoursPerWeek: 10, MaxPendingRequests: 3}
+	data, err := convertFacilityQuotaProtoToModel(expected)
+	assert.Nil(err)
+	quota, err = convertFacilityQuotaModelToProto(data)
+	assert.Nil(err)
+	assert.True(proto.Equal(expected, quota))
+
+	helper := Helper{}
+	assert.NotNil(helper.checkFacilityInput(&common.Facility{Name: "ISE", Quota: &common.FacilityQuota{MaxPendingRequests: -1}}))
+}
+
+func TestConvertOperatingHoursProtoToModel(t *testing.T) {
+	assert := assert.New(t)
+
+	operatingHours := []*common.OperatingHour{{Day: common.DayOfWeek_MON, StartHour: 10, FinishHour: 19}, {Day: common.DayOfWeek_SAT, StartHour: 2, FinishHour: 9}}
+	data, err := convertOperatingHoursProtoToModel(operatingHours)
+	assert.Nil(err)
+
+	operatingHoursProto, err := ConvertOperatingHoursModelToProto(data)
+	assert.Nil(err)
+	assert.Equal(2, len(operatingHoursProto))
+	assert.True(proto.Equal(operatingHours[0], operatingHoursProto[0]))
+	assert.True(proto.Equal(operatingHours[1], operatingHoursProto[1]))
+
+	data, err = convertOperatingHoursProtoToModel(nil)
+	assert.Nil(err)
+	assert.Equal(types.JSONText(`[]`), data)
+}
+
+func TestCheckFacilityInput(t *testing.T) {
+	assert := assert.New(t)
+	helper := Helper{}
+
+	var tests = []struct {
+		input   *common.Facility
+		isValid bool
+	}{
+		{&common.Facility{Name: "ISE", Latitude: 13.7, Longitude: 100.5}, true},
+		{&common.Facility{Name: "ISE", TimeZone: "Asia/Bangkok"}, true},
+		{&common.Facility{Name: "ISE", TimeZone: "Local"}, false},
+		{&common.Facility{Name: "ISE", TimeZone: "Asia/Atlantis"}, false},
+		{&common.Facility{Name: "ISE", OperatingHours: []*common.OperatingHour{{Day: common.DayOfWeek_MON, StartHour: 0, FinishHour: 24}}}, true},
+		{&common.Facility{Name: "ISE", Capacity: 500, Attributes: &common.FacilityAttributes{HasProjector: true}}, true},
+		{&common.Facility{Name: "ISE", Capacity: -1}, false},
+		{&common.Facility{Name: " "}, false},
+		{&common.Facility{Name: "ISE", Latitude: 90.1}, false},
+		{&common.Facility{Name: "ISE", Longitude: -180.1}, false},
+		{&common.Facility{Name: "ISE", OperatingHours: []*common.OperatingHour{{Day: 7, StartHour: 8, FinishHour: 9}}}, false},
+		{&common.Facility{Name: "ISE", OperatingHours: []*common.OperatingHour{{Day: common.DayOfWeek_MON, StartHour: 9, FinishHour: 9}}}, false},
+		{&common.Facility{Name: "ISE", OperatingHours: []*common.OperatingHour{{Day: common.DayOfWeek_MON, StartHour: 9, FinishHour: 25}}}, false},
+		{&common.Facility{Name: "ISE", OperatingHours: []*common.OperatingHour{{Day: common.DayOfWeek_MON, StartHour: 8, FinishHour: 9}, {Day: common.DayOfWeek_MON, StartHour: 10, FinishHour: 12}}}, false},
+		{&common.Facility{Name: "ISE", BookingWindow: &common.BookingWindow{MaxAdvanceDays: 90, MinLeadMinutes: 60}}, true},
+		{&common.Facility{Name: "ISE", BookingWindow: &common.BookingWindow{MinLeadMinutes: -1}}, false},
+		{&common.Facility{Name: "ISE", BookingWindow: &common.BookingWindow{MaxAdvanceDays: 1, MinLeadMinutes: 24 * 60}}, false},
+		{&common.Facility{Name: "ISE", ApprovalChain: &common.ApprovalChain{Stages: []*common.ApprovalStage{{Name: "Manager", ApproverIds: []int64{1}}}}}, true},
+		{&common.Facility{Name: "ISE", ApprovalChain: &common.ApprovalChain{Mode: common.ApprovalMode_QUORUM, Quorum: 1, Stages: []*common.ApprovalStage{{ApproverIds: []int64{1}}, {ApproverIds: []int64{2}}}}}, true},
+		{&common.Facility{Name: "ISE", ApprovalChain: &common.ApprovalChain{Mode: common.ApprovalMode_QUORUM, Quorum: 3, Stages: []*common.ApprovalStage{{ApproverIds: []int64{1}}, {ApproverIds: []int64{2}}}}}, false},
+		{&common.Facility{Name: "ISE", ApprovalChain: &common.ApprovalChain{Stages: []*common.ApprovalStage{{Name: "Manager"}}}}, false},
+		{&common.Facility{Name: "ISE", ApprovalChain: &common.ApprovalChain{Mode: 2, Stages: []*common.ApprovalStage{{ApproverIds: []int64{1}}}}}, false},
+	}
+
+	for _, test := range tests {
+		err := helper.checkFacilityInput(test.input)
+		assert.Equal(test.isValid, err == nil, test.input.String())
+	}
+}
+
+func TestCheckOperatingHourOverrideInput(t *testing.T) {
+	assert := assert.New(t)
+
+	var tests = []struct {
+		input   *common.OperatingHourOverride
+		isValid bool
+	}{
+		{&common.OperatingHourOverride{Date: "2021-04-13", IsClosed: true}, true},
+		{&common.OperatingHourOverride{Date: "2021-04-13", StartHour: 9, FinishHour: 12}, true},
+		{&common.OperatingHourOverride{Date: "13/04/2021", IsClosed: true}, false},
+		{&common.OperatingHourOverride{Date: "2021-04-13", StartHour: 12, FinishHour: 9}, false},
+		{&common.OperatingHourOverride{Date: "2021-04-13", StartHour: 9, FinishHour: 25}, false},
+	}
+
+	for _, test := range tests {
+		err := checkOperatingHourOverrideInput(test.input)
+		assert.Equal(test.isValid, err == nil, test.input.String())
+	}
+}
+
+func TestCheckDateInputSlot(t *testing.T) {
+	assert := assert.New(t)
+	helper := Helper{DayDifference: helperPkg.DayDifference}
+
+	operatingHours := make([]*common.OperatingHour, 7)
+	for i := range operatingHours {
+		operatingHours[i] = &common.OperatingHour{Day: common.DayOfWeek(i), StartHour: 8, FinishHour: 20}
+	}
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1)
+	at := func(hour int, minute int) time.Time {
+		return time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), hour, minute, 0, 0, tomorrow.Location())
+	}
+
+	var tests = []struct {
+		start       time.Time
+		finish      time.Time
+		slotMinutes int64
+		isValid     bool
+	}{
+		{at(10, 0), at(11, 0), 0, true},
+		{at(10, 30), at(11, 0), 0, false},
+		{at(10, 30), at(11, 0), 30, true},
+		{at(10, 15), at(10, 45), 15, true},
+		{at(10, 15), at(10, 45), 30, false},
+		{at(7, 45), at(9, 0), 15, false},
+		{at(19, 30), at(20, 15), 15, false},
+		{at(11, 0), at(10, 30), 30, false},
+		{at(10, 0), at(10, 0), 0, false},
+		{at(10, 30), at(10, 30), 30, false},
+	}
+
+	for _, test := range tests {
+		err := helper.checkDateInput(test.start, test.finish, &common.Facility{OperatingHours: operatingHours, SlotMinutes: test.slotMinutes}, nil, nil)
+		assert.Equal(test.isValid, err == nil, test.start.String(), test.finish.String(), test.slotMinutes)
+	}
+}
+
+func TestCheckDateInputMultiDay(t *testing.T) {
+	assert := assert.New(t)
+	helper := Helper{DayDifference: helperPkg.DayDifference}
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1)
+	at := func(day int, hour int) time.Time {
+		return time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day()+day, hour, 0, 0, 0, tomorrow.Location())
+	}
+	closedDay := at(1, 0).Weekday()
+
+	operatingHours := []*common.OperatingHour{}
+	allDays := []*common.OperatingHour{}
+	for i := 0; i < 7; i++ {
+		operatingHour := &common.OperatingHour{Day: common.DayOfWeek(i), StartHour: 8, FinishHour: 20}
+		allDays = append(allDays, operatingHour)
+		if time.Weekday(i) != closedDay {
+			operatingHours = append(operatingHours, operatingHour)
+		}
+	}
+
+	var tests = []struct {
+		start          time.Time
+		finish         time.Time
+		operatingHours []*common.OperatingHour
+		isValid        bool
+	}{
+		{at(0, 10), at(2, 18), allDays, true},
+		{at(0, 7), at(2, 18), allDays, false},
+		{at(0, 10), at(2, 21), allDays, false},
+		{at(0, 10), at(2, 18), operatingHours, false},
+		{at(2, 10), at(3, 18), operatingHours, true},
+		{at(2, 10), at(31, 18), allDays, false},
+	}
+
+	for _, test := range tests {
+		err := helper.checkDateInput(test.start, test.finish, &common.Facility{OperatingHours: test.operatingHours, SlotMinutes: 60}, nil, nil)
+		assert.Equal(test.isValid, err == nil, test.start.String(), test.finish.String())
+	}
+}
+
+func TestCheckDateInputTimeZone(t *testing.T) {
+	assert := assert.New(t)
+	helper := Helper{DayDifference: helperPkg.DayDifference}
+
+	location, _ := time.LoadLocation("Asia/Bangkok")
+	operatingHours := make([]*common.OperatingHour, 7)
+	for i := range operatingHours {
+		operatingHours[i] = &common.OperatingHour{Day: common.DayOfWeek(i), StartHour: 9, FinishHour: 18}
+	}
+	facility := &common.Facility{OperatingHours: operatingHours, TimeZone: "Asia/Bangkok"}
+
+	tomorrow := time.Now().In(location).AddDate(0, 0, 1)
+	at := func(hour int) time.Time {
+		return time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), hour, 0, 0, 0, location).UTC()
+	}
+
+	assert.Nil(helper.checkDateInput(at(9), at(18), facility, nil, nil))
+	assert.NotNil(helper.checkDateInput(at(9).Add(-time.Hour), at(18), facility, nil, nil))
+	assert.NotNil(helper.checkDateInput(at(9), at(19), facility, nil, nil))
+
+	date := at(9).In(location).Format(helperPkg.DateLayout)
+	closed := map[string]*common.OperatingHourOverride{date: {Date: date, IsClosed: true}}
+	assert.NotNil(helper.checkDateInput(at(9), at(18), facility, nil, closed))
+	shortened := map[string]*common.OperatingHourOverride{date: {Date: date, StartHour: 9, FinishHour: 12}}
+	assert.Nil(helper.checkDateInput(at(9), at(12), facility, nil, shortened))
+	assert.NotNil(helper.checkDateInput(at(9), at(18), facility, nil, shortened))
+
+	facility.TimeZone = "Mars/Olympus_Mons"
+	err := helper.checkDateInput(at(9), at(18), facility, nil, nil)
+	assert.NotNil(err)
+	assert.Equal(codes.DataLoss, err.Code())
+}
+
+func TestCheckDateInputBookingWindow(t *testing.T) {
+	assert := assert.New(t)
+	helper := Helper{DayDifference: helperPkg.DayDifference}
+
+	operatingHours := make([]*common.OperatingHour, 7)
+	for i := range operatingHours {
+		operatingHours[i] = &common.OperatingHour{Day: common.DayOfWeek(i), StartHour: 0, FinishHour: 24}
+	}
+	facility := &common.Facility{OperatingHours: operatingHours, TimeZone: "UTC"}
+	day := func(days int) time.Time {
+		now := time.Now().UTC()
+		return time.Date(now.Year(), now.Month(), now.Day()+days, 9, 0, 0, 0, time.UTC)
+	}
+
+	assert.Nil(helper.checkDateInput(day(20), day(20).Add(time.Hour), facility, nil, nil))
+	assert.NotNil(helper.checkDateInput(day(20), day(20).Add(time.Hour), facility, &common.BookingWindow{MaxAdvanceDays: 7}, nil))
+	assert.Nil(helper.checkDateInput(day(40), day(40).Add(time.Hour), facility, &common.BookingWindow{MaxAdvanceDays: 60}, nil))
+
+	err := helper.checkDateInput(day(1), day(1).Add(time.Hour), facility, &common.BookingWindow{MinLeadMinutes: 3 * 24 * 60}, nil)
+	assert.NotNil(err)
+	assert.Equal("input error: Booking must start at least 4320 minutes from now", err.Error())
+	assert.Nil(helper.checkDateInput(day(4), day(4).Add(time.Hour), facility, &common.BookingWindow{MinLeadMinutes: 3 * 24 * 60}, nil))
+
+	err = helper.checkDateInput(day(8), day(8).Add(time.Hour), facility, &common.BookingWindow{MaxAdvanceDays: 7}, nil)
+	assert.NotNil(err)
+	assert.Equal("input error: Booking date can only be within 7 days period from today", err.Error())
+}
+
+func TestCheckQuotaUnlimited(t *testing.T) {
+	assert := assert.New(t)
+
+	// facilities without quota never read usage, so no database is needed
+	start := time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC)
+	assert.Nil(checkQuota(context.Background(), nil, &common.Facility{}, 1, start, start.Add(time.Hour), nil))
+	assert.Nil(checkQuota(context.Background(), nil, &common.Facility{Quota: &common.FacilityQuota{}}, 1, start, start.Add(time.Hour), nil))
+}
+
+func TestMergeBookingWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	organizationWindow := &common.BookingWindow{MaxAdvanceDays: 30, MinLeadMinutes: 120}
+	var tests = []struct {
+		facilityWindow     *common.BookingWindow
+		organizationWindow *common.BookingWindow
+		expected           *common.BookingWindow
+	}{
+		{nil, organizationWindow, &common.BookingWindow{MaxAdvanceDays: 30, MinLeadMinutes: 120}},
+		{&common.BookingWindow{MaxAdvanceDays: 7}, organizationWindow, &common.BookingWindow{MaxAdvanceDays: 7, MinLeadMinutes: 120}},
+		{&common.BookingWindow{MinLeadMinutes: 15}, organizationWindow, &common.BookingWindow{MaxAdvanceDays: 30, MinLeadMinutes: 15}},
+		{&common.BookingWindow{MaxAdvanceDays: 7}, &common.BookingWindow{}, &common.BookingWindow{MaxAdvanceDays: 7}},
+		{nil, nil, &common.BookingWindow{}},
+	}
+
+	for _, test := range tests {
+		result := mergeBookingWindow(test.facilityWindow, test.organizationWindow)
+		assert.True(proto.Equal(test.expected, result), result.String())
+	}
+}
+
+func TestConvertFacilityRequestModelToProtoCancelled(t *testing.T) {
+	assert := assert.New(t)
+	helper := Helper{}
+
+	cancelledAt := time.Date(2021, time.February, 24, 10, 0, 0, 0, time.UTC)
+	modelFacilityRequest := model.FacilityRequest{
+		ID:          3,
+		Status:      "CANCELLED",
+		CancelledBy: sql.NullInt64{Int64: 7, Valid: true},
+		CancelledAt: sql.NullTime{Time: cancelledAt, Valid: true},
+	}
+	protoFacilityRequest := helper.convertFacilityRequestModelToProto(&modelFacilityRequest)
+	assert.Equal(common.Status_CANCELLED, protoFacilityRequest.Status)
+	assert.Equal(int64(7), protoFacilityRequest.CancelledBy.GetValue())
+	assert.True(cancelledAt.Equal(protoFacilityRequest.CancelledAt.AsTime()))
+
+	protoFacilityRequest = helper.convertFacilityRequestModelToProto(&model.FacilityRequest{Status: "PENDING"})
+	assert.Equal(common.Status_PENDING, protoFacilityRequest.Status)
+	assert.Nil(protoFacilityRequest.CancelledBy)
+	assert.Nil(protoFacilityRequest.CancelledAt)
+}
+
+func TestAllowedPreviousStatuses(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"PENDING"}, allowedPreviousStatuses(common.Status_APPROVED))
+	assert.Equal([]string{"PENDING", "WAITLISTED"}, allowedPreviousStatuses(common.Status_REJECTED))
+	assert.Equal([]string{"PENDING", "APPROVED", "WAITLISTED"}, allowedPreviousStatuses(common.Status_CANCELLED))
+	assert.Equal([]string{"WAITLISTED"}, allowedPreviousStatuses(common.Status_PENDING))
+	assert.Empty(allowedPreviousStatuses(common.Status_WAITLISTED))
+
+	err := &typing.TransitionError{From: common.Status_REJECTED, To: common.Status_APPROVED}
+	assert.Equal(codes.FailedPrecondition, err.Code())
+	assert.Equal("transition error: REJECTED request cannot be changed to APPROVED", err.Error())
+}
+
+func TestSelectWaitlistPromotions(t *testing.T) {
+	assert := assert.New(t)
+
+	at := func(hour int) time.Time {
+		return time.Date(2021, time.March, 1, hour, 0, 0, 0, time.UTC)
+	}
+	// the freed booking was from 9 to 17, the third overlaps the first
+	candidates := []*model.FacilityRequest{
+		{ID: 1, Start: at(9), Finish: at(10)},
+		{ID: 2, Start: at(14), Finish: at(15)},
+		{ID: 3, Start: at(9), Finish: at(11)},
+		{ID: 4, Start: at(10), Finish: at(11)},
+	}
+
+	var ids []int64
+	for _, request := range selectWaitlistPromotions(candidates) {
+		ids = append(ids, request.ID)
+	}
+	assert.Equal([]int64{1, 2, 4}, ids)
+	assert.Empty(selectWaitlistPromotions(nil))
+}
+
+func TestConvertFacilityRequestHistoryModelToProto(t *testing.T) {
+	assert := assert.New(t)
+
+	createdAt := time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC)
+	history := convertFacilityRequestHistoryModelToProto(&model.FacilityRequestHistory{
+		ID:                1,
+		FacilityRequestID: 2,
+		ActorID:           3,
+		OldStatus:         "PENDING",
+		NewStatus:         "REJECTED",
+		Reason:            sql.NullString{String: "Overlapped with approved request ID: 4", Valid: true},
+		CreatedAt:         createdAt,
+	})
+	assert.Equal(int64(3), history.ActorId)
+	assert.Equal(common.Status_PENDING, history.OldStatus)
+	assert.Equal(common.Status_REJECTED, history.NewStatus)
+	assert.Equal("Overlapped with approved request ID: 4", history.Reason.GetValue())
+	assert.Equal(createdAt, history.CreatedAt.AsTime())
+
+	history = convertFacilityRequestHistoryModelToProto(&model.FacilityRequestHistory{OldStatus: "APPROVED", NewStatus: "CANCELLED"})
+	assert.Nil(history.Reason)
+	assert.Equal(common.Status_CANCELLED, history.NewStatus)
+}
+
+func TestConvertApprovalChain(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := &common.ApprovalChain{
+		Mode:   common.ApprovalMode_QUORUM,
+		Quorum: 1,
+		Stages: []*common.ApprovalStage{{Name: "Building manager", ApproverIds: []int64{1, 2}}, {Name: "Safety office", ApproverIds: []int64{3}}},
+	}
+	data, err := convertApprovalChainProtoToModel(chain)
+	assert.Nil(err)
+	result, err := convertApprovalChainModelToProto(data)
+	assert.Nil(err)
+	assert.True(proto.Equal(chain, result))
+
+	data, err = convertApprovalChainProtoToModel(nil)
+	assert.Nil(err)
+	result, err = convertApprovalChainModelToProto(data)
+	assert.Nil(err)
+	assert.Nil(result)
+
+	result, err = convertApprovalChainModelToProto(nil)
+	assert.Nil(err)
+	assert.Nil(result)
+}
+
+func TestNextApprovalStage(t *testing.T) {
+	assert := assert.New(t)
+
+	stages := []*common.ApprovalStage{{Name: "Building manager", ApproverIds: []int64{1, 2}}, {Name: "Safety office", ApproverIds: []int64{3}}}
+	ordered := &common.ApprovalChain{Mode: common.ApprovalMode_ORDERED, Stages: stages}
+	quorum := &common.ApprovalChain{Mode: common.ApprovalMode_QUORUM, Quorum: 1, Stages: stages}
+	managerApproved := []*model.ApprovalDecision{{Stage: 0, ApproverID: 1, Decision: "APPROVED"}}
+
+	var tests = []struct {
+		chain      *common.ApprovalChain
+		decisions  []*model.ApprovalDecision
+		approverID int64
+		stage      int32
+		code       codes.Code
+	}{
+		{ordered, nil, 1, 0, codes.OK},
+		{ordered, nil, 3, 0, codes.FailedPrecondition},
+		{ordered, managerApproved, 3, 1, codes.OK},
+		{ordered, managerApproved, 1, 0, codes.FailedPrecondition},
+		{ordered, managerApproved, 2, 0, codes.FailedPrecondition},
+		{ordered, nil, 4, 0, codes.PermissionDenied},
+		{quorum, nil, 3, 1, codes.OK},
+		{quorum, managerApproved, 3, 1, codes.OK},
+	}
+
+	for i, test := range tests {
+		stage, err := nextApprovalStage(test.chain, test.decisions, test.approverID)
+		if test.code == codes.OK {
+			assert.Nil(err, i)
+			assert.Equal(test.stage, stage, i)
+		} else if assert.NotNil(err, i) {
+			assert.Equal(test.code, err.Code(), i)
+		}
+	}
+
+	assert.False(isApprovalChainSatisfied(ordered, managerApproved))
+	assert.True(isApprovalChainSatisfied(quorum, managerApproved))
+	assert.True(isApprovalChainSatisfied(ordered, append(managerApproved, &model.ApprovalDecision{Stage: 1, ApproverID: 3, Decision: "APPROVED"})))
+	assert.False(isApprovalChainSatisfied(quorum, []*model.ApprovalDecision{{Stage: 0, ApproverID: 1, Decision: "REJECTED"}}))
+}
+
+func TestCalendarFeedToken(t *testing.T) {
+	assert := assert.New(t)
+
+	token, err := generateCalendarFeedToken()
+	assert.Nil(err)
+	other, err := generateCalendarFeedToken()
+	assert.Nil(err)
+	assert.NotEqual(token, other)
+	assert.Len(token, 43)
+
+	assert.Equal(hashCalendarFeedToken(token), hashCalendarFeedToken(token))
+	assert.NotEqual(hashCalendarFeedToken(token), hashCalendarFeedToken(other))
+	assert.Len(hashCalendarFeedToken(token), 64)
+}
+
+func TestPageToken(t *testing.T) {
+	assert := assert.New(t)
+
+	cursor := &pageCursor{SortBy: facility.SortBy_CREATED_AT, Descending: true, Value: time.Date(2021, time.February, 24, 10, 0, 0, 123000, time.UTC), ID: 12}
+	decoded, err := decodePageToken(encodePageToken(cursor))
+	assert.Nil(err)
+	assert.Equal(cursor.SortBy, decoded.SortBy)
+	assert.Equal(cursor.Descending, decoded.Descending)
+	assert.True(cursor.Value.Equal(decoded.Value))
+	assert.Equal(cursor.ID, decoded.ID)
+
+	_, err = decodePageToken("not a token")
+	assert.NotNil(err)
+
+	assert.Equal(defaultPageSize, pageSizeOrDefault(0))
+	assert.Equal(maxPageSize, pageSizeOrDefault(1000))
+	assert.Equal(20, pageSizeOrDefault(20))
+}
+
+func TestBuildFacilityRequestListCondition(t *testing.T) {
+	assert := assert.New(t)
+
+	condition, params, err := buildFacilityRequestListCondition([]string{"r.event_id = ?"}, []interface{}{int64(4)}, nil)
+	assert.Nil(err)
+	assert.Equal("WHERE r.event_id = ? ORDER BY r.start ASC, r.id ASC LIMIT ?", condition)
+	assert.Equal([]interface{}{int64(4), defaultPageSize + 1}, params)
+
+	option := &facility.FacilityRequestListOption{
+		PageSize:   10,
+		Status:     []common.Status{common.Status_PENDING, common.Status_APPROVED},
+		FacilityId: 3,
+		Start:      timestamppb.New(time.Date(2021, time.February, 24, 0, 0, 0, 0, time.UTC)),
+		SortBy:     facility.SortBy_CREATED_AT,
+		Descending: true,
+	}
+	condition, params, err = buildFacilityRequestListCondition(nil, nil, option)
+	assert.Nil(err)
+	assert.Equal("WHERE r.status = ANY(?) AND r.facility_id = ? AND r.finish > ? ORDER BY r.created_at DESC, r.id DESC LIMIT ?", condition)
+	assert.Equal(4, len(params))
+	assert.Equal("2021-02-24 00:00:00", params[2])
+	assert.Equal(11, params[3])
+
+	option.PageToken = encodePageToken(&pageCursor{SortBy: facility.SortBy_CREATED_AT, Descending: true, Value: time.Date(2021, time.February, 25, 0, 0, 0, 0, time.UTC), ID: 9})
+	condition, params, err = buildFacilityRequestListCondition(nil, nil, option)
+	assert.Nil(err)
+	assert.Contains(condition, "(r.created_at, r.id) < (?, ?)")
+	assert.Equal(int64(9), params[4])
+
+	option.Descending = false
+	_, _, err = buildFacilityRequestListCondition(nil, nil, option)
+	assert.NotNil(err)
+}
+
+func TestBoundingBox(t *testing.T) {
+	assert := assert.New(t)
+
+	latitudeRange, longitudeRange := boundingBox(13.7, 100.5, 1000)
+	assert.InDelta(13.691, latitudeRange[0], 0.001)
+	assert.InDelta(13.709, latitudeRange[1], 0.001)
+	assert.NotNil(longitudeRange)
+	assert.InDelta(100.4907, longitudeRange[0], 0.001)
+	assert.InDelta(100.5093, longitudeRange[1], 0.001)
+
+	_, longitudeRange = boundingBox(89.99, 0, 5000)
+	assert.Nil(longitudeRange)
+
+	_, longitudeRange = boundingBox(0, 179.999, 5000)
+	assert.Nil(longitudeRange)
+}
@@ -1,13 +1,17 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/golang/protobuf/ptypes"
 	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/jmoiron/sqlx/types"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -21,14 +25,35 @@ import (
 
 	"github.com/iancoleman/strcase"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // DataService is for handling data layer
 type DataService struct {
-	SQL    *sqlx.DB
-	Helper Helper
+	SQL     *sqlx.DB
+	Helper  Helper
+	Timeout time.Duration
+	watcher *facilityRequestWatcher
 }
 
+// DefaultTimeout is deadline of a database call when POSTGRES_TIMEOUT is not set
+const DefaultTimeout = 5 * time.Second
+
+// withTimeout is function to bound ctx by the database timeout, a deadline of the caller which is sooner is kept
+func (dbs *DataService) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := dbs.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// foreignKeyViolation is postgres error code when a row is still referenced
+const foreignKeyViolation = "23503"
+
+// exclusionViolation is postgres error code when approved requests of a facility overlap
+const exclusionViolation = "23P01"
+
 const queryForRequestFacilityWithFacilty = `
 SELECT 
 r.*,
@@ -38,74 +63,1216 @@ f.latitude,
 f.longitude, 
 f.organization_id, 
 f.operating_hours,
-f.description 
+f.description,
+f.slot_minutes,
+f.time_zone,
+f.capacity,
+f.attributes 
 FROM facility_request as r
 INNER JOIN facility as f
 ON f.id = r.facility_id `
 
-// GetFacilityList is a function to get facility list owned by the organization from database
-func (dbs *DataService) GetFacilityList(organizationID int64) ([]*common.Facility, typing.CustomError) {
-	var facilities []*model.Facility
+// GetFacilityList is a function to get a page of facility list owned by the organization from database
+func (dbs *DataService) GetFacilityList(ctx context.Context, organizationID int64, pageSize int32, pageToken string) ([]*common.Facility, string, typing.CustomError) {
+	return dbs.getFacilityList(ctx, []string{"facility.organization_id = ?"}, []interface{}{organizationID}, pageSize, pageToken)
+}
+
+// GetAvailableFacilityList is a function to list a page of all available facilities
+func (dbs *DataService) GetAvailableFacilityList(ctx context.Context, pageSize int32, pageToken string) ([]*common.Facility, string, typing.CustomError) {
+	return dbs.getFacilityList(ctx, nil, nil, pageSize, pageToken)
+}
+
+func (dbs *DataService) getFacilityList(ctx context.Context, conditions []string, params []interface{}, pageSize int32, pageToken string) ([]*common.Facility, string, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	var facilities []*model.Facility
+	if pageToken != "" {
+		cursor, err := decodePageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		conditions = append(conditions, "facility.id > ?")
+		params = append(params, cursor.ID)
+	}
+
+	query := `
+	SELECT * 
+	FROM facility `
+	if len(conditions) != 0 {
+		query += "WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY facility.id LIMIT ?;"
+	limit := pageSizeOrDefault(pageSize)
+	params = append(params, limit+1)
+
+	query = dbs.SQL.Rebind(query)
+	if err := dbs.SQL.SelectContext(ctx, &facilities, query, params...); err != nil {
+		return nil, "", &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	var nextPageToken string
+	if len(facilities) > limit {
+		facilities = facilities[:limit]
+		nextPageToken = encodePageToken(&pageCursor{ID: facilities[limit-1].ID})
+	}
+
+	result := make([]*common.Facility, len(facilities))
+	for i, item := range facilities {
+		value, err := dbs.Helper.convertFacilityModelToProto(item)
+		if err != nil {
+			return nil, "", err
+		}
+		result[i] = value
+	}
+
+	return result, nextPageToken, nil
+}
+
+// queryForFacilityDistance is haversine great-circle distance in meters between facility f and a point, its parameters are latitude, latitude and longitude of the point
+const queryForFacilityDistance = `
+2 * 6371000 * ASIN(SQRT(
+	POWER(SIN(RADIANS(f.latitude - ?) / 2), 2) +
+	COS(RADIANS(?)) * COS(RADIANS(f.latitude)) * POWER(SIN(RADIANS(f.longitude - ?) / 2), 2)
+))`
+
+// queryForNoApprovedOverlap is condition that facility f has no approved request nor busy block overlapping a range, its parameters are finish and start of the range
+const queryForNoApprovedOverlap = `NOT EXISTS (
+	SELECT 1 
+	FROM (
+		SELECT r.facility_id, r.start, r.finish 
+		FROM facility_request AS r 
+		WHERE r.status = 'APPROVED' 
+		UNION ALL 
+		SELECT b.facility_id, b.start, b.finish 
+		FROM facility_busy_block AS b
+	) AS r 
+	WHERE r.facility_id = f.id 
+	AND r.start < ? AND r.finish > ?)`
+
+// queryForOpenThroughout is condition that facility f is open from start to finish of a range in its own time zone, like checkDateInput
+// date-specific overrides come before holidays and holidays before the weekly schedule, its parameters are start and finish of the range
+const queryForOpenThroughout = `NOT EXISTS (
+	SELECT 1 
+	FROM (
+		SELECT 
+			CAST(? AS timestamp) AT TIME ZONE 'UTC' AT TIME ZONE f.time_zone AS local_start, 
+			CAST(? AS timestamp) AT TIME ZONE 'UTC' AT TIME ZONE f.time_zone AS local_finish
+	) AS w 
+	CROSS JOIN generate_series(CAST(CAST(w.local_start AS date) AS timestamp), CAST(CAST(w.local_finish AS date) AS timestamp), interval '1 day') AS d(day) 
+	LEFT JOIN facility_operating_hour_override AS o 
+	ON o.facility_id = f.id AND o.date = CAST(d.day AS date) 
+	LEFT JOIN LATERAL (
+		SELECT 1 AS is_holiday 
+		FROM organization_holiday AS h 
+		WHERE h.organization_id = f.organization_id AND h.date = CAST(d.day AS date) 
+		LIMIT 1
+	) AS h ON TRUE 
+	LEFT JOIN LATERAL (
+		SELECT CAST(oh->>'start_hour' AS integer) AS start_hour, CAST(oh->>'finish_hour' AS integer) AS finish_hour 
+		FROM jsonb_array_elements(CAST(f.operating_hours AS jsonb)) AS oh 
+		WHERE oh->>'day' = to_char(d.day, 'DY')
+	) AS weekly ON TRUE 
+	WHERE CASE WHEN o.facility_id IS NOT NULL THEN o.is_closed ELSE h.is_holiday IS NOT NULL OR weekly.start_hour IS NULL END 
+	OR (d.day = CAST(w.local_start AS date) AND w.local_start < d.day + COALESCE(o.start_hour, weekly.start_hour) * interval '1 hour') 
+	OR (d.day = CAST(w.local_finish AS date) AND w.local_finish > d.day + COALESCE(o.finish_hour, weekly.finish_hour) * interval '1 hour'))`
+
+// SearchNearbyFacilities is a function to get facilities within radius ordered by distance, optionally only ones bookable for the whole range from start to finish,
+// which are open and without approved request nor busy block throughout the range like FindAvailableFacilities rather than having a free slot in it
+func (dbs *DataService) SearchNearbyFacilities(ctx context.Context, latitude float64, longitude float64, radiusMeters float64, start *timestamppb.Timestamp, finish *timestamppb.Timestamp, limit int32) ([]*facility.SearchNearbyFacilitiesResponse_Item, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	if err := checkCoordinate(latitude, longitude); err != nil {
+		return nil, err
+	}
+	if radiusMeters <= 0 {
+		return nil, &typing.InputError{Name: "Radius must be positive"}
+	}
+
+	// the bounding box lets the database use an index on coordinates before computing distance
+	latitudeRange, longitudeRange := boundingBox(latitude, longitude, radiusMeters)
+	conditions := []string{"f.latitude BETWEEN ? AND ?"}
+	params := []interface{}{latitude, latitude, longitude, latitudeRange[0], latitudeRange[1]}
+	if longitudeRange != nil {
+		conditions = append(conditions, "f.longitude BETWEEN ? AND ?")
+		params = append(params, longitudeRange[0], longitudeRange[1])
+	}
+
+	if start != nil || finish != nil {
+		if start == nil || finish == nil || !start.AsTime().Before(finish.AsTime()) {
+			return nil, &typing.InputError{Name: "Start must be earlier than Finish"}
+		}
+		layoutTime := "2006-01-02 15:04:05"
+		startText := helper.TimeStampToText(start, layoutTime)
+		finishText := helper.TimeStampToText(finish, layoutTime)
+		conditions = append(conditions, queryForNoApprovedOverlap, queryForOpenThroughout)
+		params = append(params, finishText, startText, startText, finishText)
+	}
+	params = append(params, radiusMeters, pageSizeOrDefault(limit))
+
+	var facilities []*model.FacilityWithDistance
+	query := fmt.Sprintf(`
+	SELECT * 
+	FROM (
+		SELECT f.*, %s AS distance 
+		FROM facility AS f 
+		WHERE %s
+	) AS nearby 
+	WHERE distance <= ? 
+	ORDER BY distance, id 
+	LIMIT ?;`, queryForFacilityDistance, strings.Join(conditions, " AND "))
+	query = dbs.SQL.Rebind(query)
+	if err := dbs.SQL.SelectContext(ctx, &facilities, query, params...); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	result := make([]*facility.SearchNearbyFacilitiesResponse_Item, len(facilities))
+	for i, item := range facilities {
+		value, err := dbs.Helper.convertFacilityModelToProto(&item.Facility)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = &facility.SearchNearbyFacilitiesResponse_Item{Facility: value, DistanceMeters: item.Distance}
+	}
+
+	return result, nil
+}
+
+// FindAvailableFacilities is a function to get facilities open and without approved request for the whole range, filtered and ordered by distance when radius is set
+func (dbs *DataService) FindAvailableFacilities(ctx context.Context, in *facility.FindAvailableFacilitiesRequest) ([]*facility.SearchNearbyFacilitiesResponse_Item, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	if in.Start == nil || in.End == nil || !in.Start.AsTime().Before(in.End.AsTime()) {
+		return nil, &typing.InputError{Name: "Start must be earlier than Finish"}
+	}
+
+	layoutTime := "2006-01-02 15:04:05"
+	startText := helper.TimeStampToText(in.Start, layoutTime)
+	finishText := helper.TimeStampToText(in.End, layoutTime)
+	distance := "0"
+	distanceCondition := "TRUE"
+	conditions := []string{queryForNoApprovedOverlap, queryForOpenThroughout}
+	params := []interface{}{finishText, startText, startText, finishText}
+	var distanceParams []interface{}
+
+	if in.RadiusMeters != 0 {
+		if err := checkCoordinate(in.Latitude, in.Longitude); err != nil {
+			return nil, err
+		}
+		if in.RadiusMeters < 0 {
+			return nil, &typing.InputError{Name: "Radius must be positive"}
+		}
+
+		latitudeRange, longitudeRange := boundingBox(in.Latitude, in.Longitude, in.RadiusMeters)
+		distance = queryForFacilityDistance
+		distanceCondition = "distance <= ?"
+		distanceParams = append(distanceParams, in.RadiusMeters)
+		conditions = append(conditions, "f.latitude BETWEEN ? AND ?")
+		params = append([]interface{}{in.Latitude, in.Latitude, in.Longitude}, params...)
+		params = append(params, latitudeRange[0], latitudeRange[1])
+		if longitudeRange != nil {
+			conditions = append(conditions, "f.longitude BETWEEN ? AND ?")
+			params = append(params, longitudeRange[0], longitudeRange[1])
+		}
+	}
+	if in.OrganizationId != 0 {
+		conditions = append(conditions, "f.organization_id = ?")
+		params = append(params, in.OrganizationId)
+	}
+	if in.MinCapacity != 0 {
+		conditions = append(conditions, "f.capacity >= ?")
+		params = append(params, in.MinCapacity)
+	}
+
+	params = append(params, distanceParams...)
+	params = append(params, pageSizeOrDefault(in.Limit))
+
+	var facilities []*model.FacilityWithDistance
+	query := fmt.Sprintf(`
+	SELECT * 
+	FROM (
+		SELECT f.*, %s AS distance 
+		FROM facility AS f 
+		WHERE %s
+	) AS available 
+	WHERE %s 
+	ORDER BY distance, id 
+	LIMIT ?;`, distance, strings.Join(conditions, " AND "), distanceCondition)
+	query = dbs.SQL.Rebind(query)
+	if err := dbs.SQL.SelectContext(ctx, &facilities, query, params...); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	result := make([]*facility.SearchNearbyFacilitiesResponse_Item, len(facilities))
+	for i, item := range facilities {
+		value, err := dbs.Helper.convertFacilityModelToProto(&item.Facility)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = &facility.SearchNearbyFacilitiesResponse_Item{Facility: value, DistanceMeters: item.Distance}
+	}
+
+	return result, nil
+}
+
+// GetFacilityInfo is a function to get facility’s information by id
+func (dbs *DataService) GetFacilityInfo(ctx context.Context, facilityID int64) (*common.Facility, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	var _facility model.Facility
+	query := `
+	SELECT * 
+	FROM facility 
+	WHERE facility.id = ?`
+	query = dbs.SQL.Rebind(query)
+	err := dbs.SQL.GetContext(ctx, &_facility, query, facilityID)
+
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, &typing.DatabaseError{
+			Err:        &typing.NotFoundError{Name: "facility"},
+			StatusCode: codes.NotFound,
+		}
+	case err != nil:
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	default:
+		return dbs.Helper.convertFacilityModelToProto(&_facility)
+	}
+}
+
+// CreateFacility is a function to create facility owned by the organization
+func (dbs *DataService) CreateFacility(ctx context.Context, data *common.Facility) (*common.Facility, typing.CustomError) {
+	if err := dbs.Helper.checkFacilityInput(data); err != nil {
+		return nil, err
+	}
+
+	operatingHours, err := convertOperatingHoursProtoToModel(data.OperatingHours)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes, err := convertFacilityAttributesProtoToModel(data.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	approvalChain, err := convertApprovalChainProtoToModel(data.ApprovalChain)
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := convertFacilityQuotaProtoToModel(data.Quota)
+	if err != nil {
+		return nil, err
+	}
+
+	bookingWindow, err := convertBookingWindowProtoToModel(data.BookingWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	INSERT INTO facility (organization_id, name, latitude, longitude, operating_hours, description, slot_minutes, time_zone, capacity, attributes, approval_chain, quota, booking_window) 
+	VALUES (:organization_id, :name, :latitude, :longitude, :operating_hours, :description, :slot_minutes, :time_zone, :capacity, :attributes, :approval_chain, :quota, :booking_window) 
+	RETURNING *`
+	return dbs.writeFacility(ctx, query, map[string]interface{}{
+		"organization_id": data.OrganizationId,
+		"name":            data.Name,
+		"latitude":        data.Latitude,
+		"longitude":       data.Longitude,
+		"operating_hours": operatingHours,
+		"description":     data.Description,
+		"slot_minutes":    helper.SlotMinutesOrDefault(data.SlotMinutes),
+		"time_zone":       helper.TimeZoneOrDefault(data.TimeZone),
+		"capacity":        data.Capacity,
+		"attributes":      attributes,
+		"approval_chain":  approvalChain,
+		"quota":           quota,
+		"booking_window":  bookingWindow,
+	})
+}
+
+// UpdateFacility is a function to update facility’s information by id, approval chain, quota and booking window are kept when they are nil
+func (dbs *DataService) UpdateFacility(ctx context.Context, data *common.Facility) (*common.Facility, typing.CustomError) {
+	if err := dbs.Helper.checkFacilityInput(data); err != nil {
+		return nil, err
+	}
+
+	operatingHours, err := convertOperatingHoursProtoToModel(data.OperatingHours)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes, err := convertFacilityAttributesProtoToModel(data.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	// policies not sent are NULL so COALESCE keeps the stored ones, an empty message clears its policy
+	var approvalChain, quota, bookingWindow interface{}
+	if data.ApprovalChain != nil {
+		if approvalChain, err = convertApprovalChainProtoToModel(data.ApprovalChain); err != nil {
+			return nil, err
+		}
+	}
+	if data.Quota != nil {
+		if quota, err = convertFacilityQuotaProtoToModel(data.Quota); err != nil {
+			return nil, err
+		}
+	}
+	if data.BookingWindow != nil {
+		if bookingWindow, err = convertBookingWindowProtoToModel(data.BookingWindow); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+	UPDATE facility 
+	SET name=:name, latitude=:latitude, longitude=:longitude, operating_hours=:operating_hours, description=:description, slot_minutes=:slot_minutes, time_zone=:time_zone, capacity=:capacity, attributes=:attributes, approval_chain=COALESCE(:approval_chain, approval_chain), quota=COALESCE(:quota, quota), booking_window=COALESCE(:booking_window, booking_window) 
+	WHERE facility.id = :id 
+	RETURNING *`
+	return dbs.writeFacility(ctx, query, map[string]interface{}{
+		"id":              data.Id,
+		"name":            data.Name,
+		"latitude":        data.Latitude,
+		"longitude":       data.Longitude,
+		"operating_hours": operatingHours,
+		"description":     data.Description,
+		"slot_minutes":    helper.SlotMinutesOrDefault(data.SlotMinutes),
+		"time_zone":       helper.TimeZoneOrDefault(data.TimeZone),
+		"capacity":        data.Capacity,
+		"attributes":      attributes,
+		"approval_chain":  approvalChain,
+		"quota":           quota,
+		"booking_window":  bookingWindow,
+	})
+}
+
+func (dbs *DataService) writeFacility(ctx context.Context, query string, arg map[string]interface{}) (*common.Facility, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	var _facility model.Facility
+	rows, err := dbs.SQL.NamedQueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, &typing.DatabaseError{
+			Err:        &typing.NotFoundError{Name: "facility"},
+			StatusCode: codes.NotFound,
+		}
+	}
+	if err := rows.StructScan(&_facility); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	return dbs.Helper.convertFacilityModelToProto(&_facility)
+}
+
+// DeleteFacility is a function to delete facility by id
+func (dbs *DataService) DeleteFacility(ctx context.Context, facilityID int64) typing.CustomError {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+	DELETE FROM facility 
+	WHERE facility.id = ?`
+	query = dbs.SQL.Rebind(query)
+	result, err := dbs.SQL.ExecContext(ctx, query, facilityID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == foreignKeyViolation {
+		return &typing.DatabaseError{
+			Err:        &typing.InputError{Name: "Facility still has facility requests"},
+			StatusCode: codes.FailedPrecondition,
+		}
+	}
+	if err != nil {
+		return &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	count, err := result.RowsAffected()
+	switch {
+	case err != nil:
+		return &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	case count != 1:
+		return &typing.DatabaseError{
+			Err:        &typing.NotFoundError{Name: "facility"},
+			StatusCode: codes.NotFound,
+		}
+	default:
+		return nil
+	}
+}
+
+func (dbs *DataService) updateFacilityRequest(ctx context.Context, requestID int64, userID int64, status common.Status, reason *wrapperspb.StringValue) typing.CustomError {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := dbs.SQL.BeginTxx(ctx, nil)
+	if err != nil {
+		return &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	oldStatus, transitErr := transitFacilityRequest(ctx, tx, requestID, userID, status, reason)
+	if transitErr != nil {
+		return transitErr
+	}
+
+	if status == common.Status_REJECTED {
+		if err := recordRejectionDecision(ctx, tx, requestID, userID, reason); err != nil {
+			return err
+		}
+	}
+
+	if err := insertFacilityRequestHistory(ctx, tx, []int64{requestID}, userID, oldStatus, status, reason); err != nil {
+		return err
+	}
+
+	changedIDs := []int64{requestID}
+	if status == common.Status_CANCELLED || status == common.Status_REJECTED {
+		promotedIDs, err := promoteWaitlistedFacilityRequests(ctx, tx, requestID)
+		if err != nil {
+			return err
+		}
+		changedIDs = append(changedIDs, promotedIDs...)
+	}
+
+	// requesters of promoted requests are notified through WatchFacilityRequests
+	if err := notifyFacilityRequestChanges(ctx, tx, changedIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	return nil
+}
+
+// transitFacilityRequest is function to change status of facility request in the transaction and return the old status,
+// the update only matches a status allowed by facilityRequestTransitions so concurrent changes cannot make an illegal transition
+func transitFacilityRequest(ctx context.Context, tx *sqlx.Tx, requestID int64, userID int64, status common.Status, reason *wrapperspb.StringValue) (string, typing.CustomError) {
+	var querySet string
+	if reason != nil {
+		querySet += ", reject_reason = :reason"
+	}
+	if status == common.Status_CANCELLED {
+		querySet += ", cancelled_by = :user_id, cancelled_at = NOW()"
+	}
+
+	query := fmt.Sprintf(`
+	WITH old AS (
+		SELECT id, status 
+		FROM facility_request 
+		WHERE id = :id 
+		FOR UPDATE
+	) 
+	UPDATE facility_request AS r 
+	SET status = :status%s 
+	FROM old 
+	WHERE r.id = old.id AND old.status = ANY(:from) 
+	RETURNING old.status`,
+		querySet)
+	query, args, err := tx.BindNamed(query, map[string]interface{}{
+		"id":      requestID,
+		"user_id": userID,
+		"status":  status.String(),
+		"reason":  reason.GetValue(),
+		"from":    pq.Array(allowedPreviousStatuses(status)),
+	})
+	if err != nil {
+		return "", &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	var oldStatus string
+	err = tx.GetContext(ctx, &oldStatus, query, args...)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == exclusionViolation {
+		return "", &typing.AlreadyExistError{Name: "Facility is booked at that time"}
+	}
+	switch {
+	case err == sql.ErrNoRows:
+		return "", explainFailedTransition(ctx, tx, requestID, status)
+	case err != nil:
+		return "", &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	default:
+		return oldStatus, nil
+	}
+}
+
+// explainFailedTransition is function to tell whether facility request is missing or its current status cannot be changed to the status
+func explainFailedTransition(ctx context.Context, tx *sqlx.Tx, requestID int64, status common.Status) typing.CustomError {
+	var currentStatus string
+	query := tx.Rebind(`
+	SELECT status 
+	FROM facility_request 
+	WHERE id = ?`)
+	err := tx.GetContext(ctx, &currentStatus, query, requestID)
+	switch {
+	case err == sql.ErrNoRows:
+		return &typing.DatabaseError{
+			Err:        &typing.NotFoundError{Name: "FacilityRequest"},
+			StatusCode: codes.NotFound,
+		}
+	case err != nil:
+		return &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	default:
+		return &typing.TransitionError{From: common.Status(common.Status_value[currentStatus]), To: status}
+	}
+}
+
+// insertFacilityRequestHistory is function to record the same status transition of facility requests by the actor in the transaction
+func insertFacilityRequestHistory(ctx context.Context, execer sqlx.ExecerContext, requestIDs []int64, actorID int64, oldStatus string, newStatus common.Status, reason *wrapperspb.StringValue) typing.CustomError {
+	query := sqlx.Rebind(sqlx.DOLLAR, `
+	INSERT INTO facility_request_history (facility_request_id, actor_id, old_status, new_status, reason) 
+	SELECT id, ?, ?, ?, ? 
+	FROM UNNEST(CAST(? AS bigint[])) AS id`)
+	queryReason := sql.NullString{String: reason.GetValue(), Valid: reason != nil}
+	if _, err := execer.ExecContext(ctx, query, actorID, oldStatus, newStatus.String(), queryReason, pq.Array(requestIDs)); err != nil {
+		return &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	return nil
+}
+
+// promoteWaitlistedFacilityRequests is function to change waitlisted requests overlapping with the freed request to pending once no approved request nor busy block overlaps them,
+// the earliest waitlisted ones are promoted first and the promotion is recorded with actor 0 as it is done by the system
+func promoteWaitlistedFacilityRequests(ctx context.Context, tx *sqlx.Tx, freedRequestID int64) ([]int64, typing.CustomError) {
+	var candidates []*model.FacilityRequest
+	query := tx.Rebind(`
+	SELECT w.* 
+	FROM facility_request AS w 
+	INNER JOIN facility_request AS freed 
+	ON freed.facility_id = w.facility_id AND freed.start < w.finish AND freed.finish > w.start 
+	WHERE freed.id = ? 
+	AND w.status = 'WAITLISTED' 
+	AND w.start > ? 
+	AND NOT EXISTS (
+		SELECT 1 
+		FROM facility_request AS a 
+		WHERE a.facility_id = w.facility_id AND a.status = 'APPROVED' AND a.start < w.finish AND a.finish > w.start
+	) 
+	AND NOT EXISTS (
+		SELECT 1 
+		FROM facility_busy_block AS b 
+		WHERE b.facility_id = w.facility_id AND b.start < w.finish AND b.finish > w.start
+	) 
+	ORDER BY w.created_at, w.id 
+	FOR UPDATE OF w SKIP LOCKED`)
+	layoutTime := "2006-01-02 15:04:05"
+	if err := tx.SelectContext(ctx, &candidates, query, freedRequestID, time.Now().UTC().Format(layoutTime)); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	promoted := selectWaitlistPromotions(candidates)
+	promotedIDs := make([]int64, len(promoted))
+	reason := &wrapperspb.StringValue{Value: fmt.Sprintf("Promoted from waitlist after request ID: %d was freed", freedRequestID)}
+	for i, request := range promoted {
+		oldStatus, transitErr := transitFacilityRequest(ctx, tx, request.ID, 0, common.Status_PENDING, nil)
+		if transitErr != nil {
+			return nil, transitErr
+		}
+		if err := insertFacilityRequestHistory(ctx, tx, []int64{request.ID}, 0, oldStatus, common.Status_PENDING, reason); err != nil {
+			return nil, err
+		}
+		promotedIDs[i] = request.ID
+	}
+
+	return promotedIDs, nil
+}
+
+// RejectFacilityRequest is a function to reject facility’s request by id
+func (dbs *DataService) RejectFacilityRequest(ctx context.Context, requestID int64, userID int64, reason *wrapperspb.StringValue) typing.CustomError {
+	return dbs.updateFacilityRequest(ctx, requestID, userID, common.Status_REJECTED, reason)
+}
+
+// ApproveFacilityRequest is a function to approve facility request and reject pending requests overlapping with it in one transaction,
+// when the facility has an approval chain the user's decision is recorded and the request is approved only once the chain is satisfied
+func (dbs *DataService) ApproveFacilityRequest(ctx context.Context, requestID int64, userID int64) (bool, []int64, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := dbs.SQL.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var facilityID int64
+	query := tx.Rebind(`
+	SELECT facility_id 
+	FROM facility_request 
+	WHERE id = ?`)
+	err = tx.GetContext(ctx, &facilityID, query, requestID)
+	switch {
+	case err == sql.ErrNoRows:
+		return false, nil, &typing.DatabaseError{
+			Err:        &typing.NotFoundError{Name: "FacilityRequest"},
+			StatusCode: codes.NotFound,
+		}
+	case err != nil:
+		return false, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	// approvals of the same facility are serialized by locking the facility row before any request row
+	var approvalChain types.JSONText
+	query = tx.Rebind(`
+	SELECT approval_chain 
+	FROM facility 
+	WHERE id = ? 
+	FOR UPDATE`)
+	if err := tx.GetContext(ctx, &approvalChain, query, facilityID); err != nil {
+		return false, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	chain, chainErr := convertApprovalChainModelToProto(approvalChain)
+	if chainErr != nil {
+		return false, nil, chainErr
+	}
+
+	var facilityRequest model.FacilityRequest
+	query = tx.Rebind(`
+	SELECT * 
+	FROM facility_request 
+	WHERE id = ? 
+	FOR UPDATE`)
+	if err := tx.GetContext(ctx, &facilityRequest, query, requestID); err != nil {
+		return false, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	if chain != nil {
+		isSatisfied, err := decideApprovalStage(ctx, tx, &facilityRequest, chain, userID)
+		if err != nil {
+			return false, nil, err
+		}
+		if !isSatisfied {
+			if err := tx.Commit(); err != nil {
+				return false, nil, &typing.DatabaseError{
+					Err:        err,
+					StatusCode: codes.Internal,
+				}
+			}
+			return false, nil, nil
+		}
+	}
+
+	layoutTime := "2006-01-02 15:04:05"
+	startTimeText := facilityRequest.Start.UTC().Format(layoutTime)
+	finishTimeText := facilityRequest.Finish.UTC().Format(layoutTime)
+
+	var count int64
+	query = tx.Rebind(`
+	SELECT COUNT(*) 
+	FROM facility_request 
+	WHERE start < ? AND finish > ? 
+	AND facility_id = ? 
+	AND status = 'APPROVED' 
+	AND id <> ?;`)
+	if err := tx.GetContext(ctx, &count, query, finishTimeText, startTimeText, facilityID, requestID); err != nil {
+		return false, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	if count != 0 {
+		return false, nil, &typing.AlreadyExistError{Name: "Facility is booked at that time"}
+	}
+
+	query = tx.Rebind(`
+	SELECT COUNT(*) 
+	FROM facility_busy_block 
+	WHERE start < ? AND finish > ? 
+	AND facility_id = ?;`)
+	if err := tx.GetContext(ctx, &count, query, finishTimeText, startTimeText, facilityID); err != nil {
+		return false, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	if count != 0 {
+		return false, nil, &typing.AlreadyExistError{Name: "Facility is blocked by external calendar at that time"}
+	}
+
+	oldStatus, transitErr := transitFacilityRequest(ctx, tx, requestID, userID, common.Status_APPROVED, nil)
+	if transitErr != nil {
+		return false, nil, transitErr
+	}
+
+	var rejectedIDs []int64
+	query = tx.Rebind(`
+	UPDATE facility_request 
+	SET status = 'REJECTED', reject_reason = ? 
+	WHERE start < ? AND finish > ? 
+	AND facility_id = ? 
+	AND status = 'PENDING' 
+	AND id <> ? 
+	RETURNING id`)
+	reason := fmt.Sprintf("Overlapped with approved request ID: %d", requestID)
+	if err := tx.SelectContext(ctx, &rejectedIDs, query, reason, finishTimeText, startTimeText, facilityID, requestID); err != nil {
+		return false, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	if err := insertFacilityRequestHistory(ctx, tx, []int64{requestID}, userID, oldStatus, common.Status_APPROVED, nil); err != nil {
+		return false, nil, err
+	}
+	rejectReason := &wrapperspb.StringValue{Value: reason}
+	if err := insertFacilityRequestHistory(ctx, tx, rejectedIDs, userID, common.Status_PENDING.String(), common.Status_REJECTED, rejectReason); err != nil {
+		return false, nil, err
+	}
+
+	if err := notifyFacilityRequestChanges(ctx, tx, append([]int64{requestID}, rejectedIDs...)); err != nil {
+		return false, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	return true, rejectedIDs, nil
+}
+
+// decideApprovalStage is function to record approval of the user for the next stage of the chain and check if the chain is satisfied
+func decideApprovalStage(ctx context.Context, tx *sqlx.Tx, facilityRequest *model.FacilityRequest, chain *common.ApprovalChain, userID int64) (bool, typing.CustomError) {
+	if facilityRequest.Status != common.Status_PENDING.String() {
+		return false, &typing.TransitionError{From: common.Status(common.Status_value[facilityRequest.Status]), To: common.Status_APPROVED}
+	}
+
+	var decisions []*model.ApprovalDecision
+	query := tx.Rebind(`
+	SELECT * 
+	FROM facility_request_approval 
+	WHERE facility_request_id = ?`)
+	if err := tx.SelectContext(ctx, &decisions, query, facilityRequest.ID); err != nil {
+		return false, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	stage, err := nextApprovalStage(chain, decisions, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := insertApprovalDecision(ctx, tx, facilityRequest.ID, stage, userID, common.Status_APPROVED, nil); err != nil {
+		return false, err
+	}
+
+	decisions = append(decisions, &model.ApprovalDecision{Stage: stage, ApproverID: userID, Decision: common.Status_APPROVED.String()})
+	return isApprovalChainSatisfied(chain, decisions), nil
+}
+
+// recordRejectionDecision is function to record rejection of the user when the user is an approver of the request's facility, the rejection ends the chain
+func recordRejectionDecision(ctx context.Context, tx *sqlx.Tx, requestID int64, userID int64, reason *wrapperspb.StringValue) typing.CustomError {
+	var approvalChain types.JSONText
+	query := tx.Rebind(`
+	SELECT f.approval_chain 
+	FROM facility AS f 
+	INNER JOIN facility_request AS r 
+	ON r.facility_id = f.id 
+	WHERE r.id = ?`)
+	if err := tx.GetContext(ctx, &approvalChain, query, requestID); err != nil {
+		return &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	chain, err := convertApprovalChainModelToProto(approvalChain)
+	if err != nil {
+		return err
+	}
+
+	stage := helper.ApproverStage(chain, userID)
+	if stage < 0 {
+		return nil
+	}
+	return insertApprovalDecision(ctx, tx, requestID, stage, userID, common.Status_REJECTED, reason)
+}
+
+func insertApprovalDecision(ctx context.Context, tx *sqlx.Tx, requestID int64, stage int32, userID int64, decision common.Status, reason *wrapperspb.StringValue) typing.CustomError {
+	query := tx.Rebind(`
+	INSERT INTO facility_request_approval (facility_request_id, stage, approver_id, decision, reason) 
+	VALUES (?, ?, ?, ?, ?)`)
+	queryReason := sql.NullString{String: reason.GetValue(), Valid: reason != nil}
+	if _, err := tx.ExecContext(ctx, query, requestID, stage, userID, decision.String(), queryReason); err != nil {
+		return &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	return nil
+}
+
+// GetApprovalDecisions is function to get decisions of the approval chain for facility request, oldest first
+func (dbs *DataService) GetApprovalDecisions(ctx context.Context, requestID int64) ([]*facility.ApprovalDecision, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	var decisions []*model.ApprovalDecision
+	query := `
+	SELECT * 
+	FROM facility_request_approval 
+	WHERE facility_request_id = ? 
+	ORDER BY created_at, stage`
+	query = dbs.SQL.Rebind(query)
+	if err := dbs.SQL.SelectContext(ctx, &decisions, query, requestID); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	result := make([]*facility.ApprovalDecision, len(decisions))
+	for i, item := range decisions {
+		result[i] = convertApprovalDecisionModelToProto(item)
+	}
+
+	return result, nil
+}
+
+// CancelFacilityRequest is a function to cancel pending or approved facility request by the user
+func (dbs *DataService) CancelFacilityRequest(ctx context.Context, requestID int64, userID int64) typing.CustomError {
+	return dbs.updateFacilityRequest(ctx, requestID, userID, common.Status_CANCELLED, nil)
+}
+
+// CreateFacilityRequest is a function to create facilityRequest of the event organized by the requester organization, the request is either pending or waitlisted,
+// facility's quota is checked in the same transaction
+func (dbs *DataService) CreateFacilityRequest(ctx context.Context, eventID int64, requesterOrganizationID int64, facilityID int64, start *timestamppb.Timestamp, finish *timestamppb.Timestamp, status common.Status) (*common.FacilityRequest, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := dbs.SQL.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	startTime, _ := ptypes.Timestamp(start)
+	finishTime, _ := ptypes.Timestamp(finish)
+	facilityInfo, lockErr := dbs.lockFacility(ctx, tx, facilityID)
+	if lockErr != nil {
+		return nil, lockErr
+	}
+	if err := checkQuota(ctx, tx, facilityInfo, requesterOrganizationID, startTime, finishTime, nil); err != nil {
+		return nil, err
+	}
+
+	var id int64
+	query := tx.Rebind(`
+	INSERT INTO facility_request (event_id, requester_organization_id, facility_id, status, start, finish) 
+	VALUES (?, ?, ?, ?, ?, ?) 
+	RETURNING id`)
+	if err := tx.GetContext(ctx, &id, query, eventID, requesterOrganizationID, facilityID, status.String(), startTime, finishTime); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	if err := notifyFacilityRequestChanges(ctx, tx, []int64{id}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	result := common.FacilityRequest{
+		Id:                      id,
+		EventId:                 eventID,
+		FacilityId:              facilityID,
+		Status:                  status,
+		Start:                   start,
+		Finish:                  finish,
+		RequesterOrganizationId: requesterOrganizationID,
+	}
+	return &result, nil
+}
+
+// CreateFacilityRequestSeries is a function to create recurring facilityRequest series and its occurrences in one transaction, facility's quota is checked in the same transaction
+func (dbs *DataService) CreateFacilityRequestSeries(ctx context.Context, eventID int64, requesterOrganizationID int64, facilityID int64, rule string, occurrences []*common.FacilityRequest) (int64, []*common.FacilityRequest, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := dbs.SQL.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	facilityInfo, lockErr := dbs.lockFacility(ctx, tx, facilityID)
+	if lockErr != nil {
+		return 0, nil, lockErr
+	}
+	for i, occurrence := range occurrences {
+		if err := checkQuota(ctx, tx, facilityInfo, requesterOrganizationID, occurrence.Start.AsTime(), occurrence.Finish.AsTime(), occurrences[:i]); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var seriesID int64
+	query := tx.Rebind(`
+	INSERT INTO facility_request_series (event_id, facility_id, rule) 
+	VALUES (?, ?, ?) 
+	RETURNING id`)
+	if err := tx.GetContext(ctx, &seriesID, query, eventID, facilityID, rule); err != nil {
+		return 0, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	query = tx.Rebind(`
+	INSERT INTO facility_request (event_id, requester_organization_id, facility_id, status, start, finish, series_id) 
+	VALUES (?, ?, ?, ?, ?, ?, ?) 
+	RETURNING id`)
+	result := make([]*common.FacilityRequest, len(occurrences))
+	for i, occurrence := range occurrences {
+		var id int64
+		startTime, _ := ptypes.Timestamp(occurrence.Start)
+		finishTime, _ := ptypes.Timestamp(occurrence.Finish)
+		if err := tx.GetContext(ctx, &id, query, eventID, requesterOrganizationID, facilityID, common.Status_PENDING.String(), startTime, finishTime, seriesID); err != nil {
+			return 0, nil, &typing.DatabaseError{
+				Err:        err,
+				StatusCode: codes.Internal,
+			}
+		}
+		result[i] = &common.FacilityRequest{
+			Id:                      id,
+			EventId:                 eventID,
+			FacilityId:              facilityID,
+			Status:                  common.Status_PENDING,
+			Start:                   occurrence.Start,
+			Finish:                  occurrence.Finish,
+			SeriesId:                seriesID,
+			RequesterOrganizationId: requesterOrganizationID,
+		}
+	}
+
+	requestIDs := make([]int64, len(result))
+	for i, occurrence := range result {
+		requestIDs[i] = occurrence.Id
+	}
+	if err := notifyFacilityRequestChanges(ctx, tx, requestIDs); err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	return seriesID, result, nil
+}
+
+// GetFacilityRequestSeries is function to get occurrences of recurring facility request series ordered by start
+func (dbs *DataService) GetFacilityRequestSeries(ctx context.Context, seriesID int64) ([]*common.FacilityRequest, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	var facilitieRequests []*model.FacilityRequest
 	query := `
 	SELECT * 
-	FROM facility 
-	WHERE facility.organization_id = ?;`
-
+	FROM facility_request 
+	WHERE series_id = ? 
+	ORDER BY start;`
 	query = dbs.SQL.Rebind(query)
-	if err := dbs.SQL.Select(&facilities, query, organizationID); err != nil {
+	if err := dbs.SQL.SelectContext(ctx, &facilitieRequests, query, seriesID); err != nil {
 		return nil, &typing.DatabaseError{
 			Err:        err,
 			StatusCode: codes.Internal,
 		}
 	}
-	result := make([]*common.Facility, len(facilities))
-	for i, item := range facilities {
-		value, err := dbs.Helper.convertFacilityModelToProto(item)
-		if err != nil {
-			return nil, err
+
+	if len(facilitieRequests) == 0 {
+		return nil, &typing.DatabaseError{
+			Err:        &typing.NotFoundError{Name: "FacilityRequestSeries"},
+			StatusCode: codes.NotFound,
 		}
-		result[i] = value
+	}
+
+	result := make([]*common.FacilityRequest, len(facilitieRequests))
+	for i, item := range facilitieRequests {
+		result[i] = dbs.Helper.convertFacilityRequestModelToProto(item)
 	}
 
 	return result, nil
 }
 
-// GetAvailableFacilityList is a function to list all available facilities
-func (dbs *DataService) GetAvailableFacilityList() ([]*common.Facility, typing.CustomError) {
-	var facilities []*model.Facility
-	query := `
-	SELECT * 
-	FROM facility`
+// GetQuotaUsage is function to get pending, waitlisted and approved minutes of the requester organization in the week and its pending requests of the facility,
+// waitlisted requests count as pending since they are promoted to pending without checking quota again
+func (dbs *DataService) GetQuotaUsage(ctx context.Context, facilityID int64, requesterOrganizationID int64, weekStart time.Time, weekFinish time.Time) (*model.QuotaUsage, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
 
-	if err := dbs.SQL.Select(&facilities, query); err != nil {
+	return getQuotaUsage(ctx, dbs.SQL, facilityID, requesterOrganizationID, weekStart, weekFinish)
+}
+
+// getQuotaUsage is function to get quota usage of the requester organization in the week with the queryer, which may be a transaction
+func getQuotaUsage(ctx context.Context, queryer sqlx.QueryerContext, facilityID int64, requesterOrganizationID int64, weekStart time.Time, weekFinish time.Time) (*model.QuotaUsage, typing.CustomError) {
+	var usage model.QuotaUsage
+	query := `
+	SELECT 
+	CAST(COALESCE(SUM(EXTRACT(EPOCH FROM LEAST(finish, :week_finish) - GREATEST(start, :week_start)) / 60) 
+		FILTER (WHERE status IN ('PENDING', 'APPROVED', 'WAITLISTED') AND start < :week_finish AND finish > :week_start), 0) AS bigint) AS booked_minutes, 
+	COUNT(*) FILTER (WHERE status IN ('PENDING', 'WAITLISTED')) AS pending_requests 
+	FROM facility_request 
+	WHERE facility_id = :facility_id AND requester_organization_id = :requester_organization_id`
+	layoutTime := "2006-01-02 15:04:05"
+	query, args, err := sqlx.Named(query, map[string]interface{}{
+		"facility_id":               facilityID,
+		"requester_organization_id": requesterOrganizationID,
+		"week_start":                weekStart.UTC().Format(layoutTime),
+		"week_finish":               weekFinish.UTC().Format(layoutTime),
+	})
+	if err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	query = sqlx.Rebind(sqlx.DOLLAR, query)
+	if err := sqlx.GetContext(ctx, queryer, &usage, query, args...); err != nil {
 		return nil, &typing.DatabaseError{
 			Err:        err,
 			StatusCode: codes.Internal,
 		}
 	}
 
-	result := make([]*common.Facility, len(facilities))
-	for i, item := range facilities {
-		value, err := dbs.Helper.convertFacilityModelToProto(item)
+	return &usage, nil
+}
+
+// CheckQuota is function to refuse a request exceeding facility's quota of the requester organization, planned requests are not created yet but counted,
+// the result is advisory since creating requests checks quota again in its transaction
+func (dbs *DataService) CheckQuota(ctx context.Context, facility *common.Facility, requesterOrganizationID int64, start time.Time, finish time.Time, planned []*common.FacilityRequest) typing.CustomError {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	return checkQuota(ctx, dbs.SQL, facility, requesterOrganizationID, start, finish, planned)
+}
+
+// checkQuota is function to refuse a request exceeding facility's quota of the requester organization in any week it covers, planned requests are not created yet but counted,
+// creating requests calls it in the transaction holding the facility row so concurrent requests can not both fit the last allowance
+func checkQuota(ctx context.Context, queryer sqlx.QueryerContext, facility *common.Facility, requesterOrganizationID int64, start time.Time, finish time.Time, planned []*common.FacilityRequest) typing.CustomError {
+	quota := facility.Quota
+	if quota.GetMaxHoursPerWeek() == 0 && quota.GetMaxPendingRequests() == 0 {
+		return nil
+	}
+
+	location, locationErr := time.LoadLocation(facility.TimeZone)
+	if locationErr != nil {
+		return &typing.DatabaseError{Err: locationErr, StatusCode: codes.DataLoss}
+	}
+
+	for weekStart := helper.WeekStart(start.In(location)); weekStart.Before(finish); weekStart = weekStart.AddDate(0, 0, 7) {
+		weekFinish := weekStart.AddDate(0, 0, 7)
+		usage, err := getQuotaUsage(ctx, queryer, facility.Id, requesterOrganizationID, weekStart, weekFinish)
 		if err != nil {
-			return nil, err
+			return err
+		}
+
+		pendingRequests := usage.PendingRequests + int64(len(planned)) + 1
+		if quota.MaxPendingRequests > 0 && pendingRequests > int64(quota.MaxPendingRequests) {
+			return &typing.QuotaError{Name: "pending requests", Limit: int64(quota.MaxPendingRequests), Usage: pendingRequests}
+		}
+
+		bookedMinutes := usage.BookedMinutes + helper.OverlapMinutes(start, finish, weekStart, weekFinish)
+		for _, request := range planned {
+			bookedMinutes += helper.OverlapMinutes(request.Start.AsTime(), request.Finish.AsTime(), weekStart, weekFinish)
+		}
+		if quota.MaxHoursPerWeek > 0 && bookedMinutes > int64(quota.MaxHoursPerWeek)*60 {
+			return &typing.QuotaError{Name: "minutes in the week of " + weekStart.Format(helper.DateLayout), Limit: int64(quota.MaxHoursPerWeek) * 60, Usage: bookedMinutes}
 		}
-		result[i] = value
 	}
 
-	return result, nil
+	return nil
 }
 
-// GetFacilityInfo is a function to get facility’s information by id
-func (dbs *DataService) GetFacilityInfo(facilityID int64) (*common.Facility, typing.CustomError) {
+// lockFacility is function to get the facility and lock its row until the transaction ends, requests changing usage of the facility are serialized by it
+func (dbs *DataService) lockFacility(ctx context.Context, tx *sqlx.Tx, facilityID int64) (*common.Facility, typing.CustomError) {
 	var _facility model.Facility
-	query := `
+	query := tx.Rebind(`
 	SELECT * 
 	FROM facility 
-	WHERE facility.id = ?`
-	query = dbs.SQL.Rebind(query)
-	err := dbs.SQL.Get(&_facility, query, facilityID)
-
+	WHERE id = ? 
+	FOR UPDATE`)
+	err := tx.GetContext(ctx, &_facility, query, facilityID)
 	switch {
 	case err == sql.ErrNoRows:
 		return nil, &typing.DatabaseError{
@@ -122,22 +1289,201 @@ func (dbs *DataService) GetFacilityInfo(facilityID int64) (*common.Facility, typ
 	}
 }
 
-func (dbs *DataService) updateFacilityRequest(requestID int64, status common.Status, reason *wrapperspb.StringValue) typing.CustomError {
-	var queryReason string
-	if reason != nil {
-		queryReason = ", reject_reason=:reason "
+// IsOverlapTime is function to check whether time is overlap with already booked facility
+func (dbs *DataService) IsOverlapTime(ctx context.Context, facilityID int64, start *timestamppb.Timestamp, finish *timestamppb.Timestamp, checkTimeIntegrity bool) (bool, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	facility, facilityNotFoundError := dbs.GetFacilityInfo(ctx, facilityID)
+	if facilityNotFoundError != nil {
+		return false, facilityNotFoundError
 	}
 
-	query := fmt.Sprintf(`
-	UPDATE facility_request 
-	SET status=:status%s 
-	WHERE facility_request.id = :id`,
-		queryReason)
-	result, err := dbs.SQL.NamedExec(query, map[string]interface{}{
-		"id":     requestID,
-		"status": status.String(),
-		"reason": reason.GetValue(),
+	var count int64
+	startTime, _ := ptypes.Timestamp(start)
+	finishTime, _ := ptypes.Timestamp(finish)
+	if checkTimeIntegrity {
+		// a day is added to both ends so every date in facility's time zone is covered
+		overrides, err := dbs.GetOperatingHourOverrides(ctx, facilityID, startTime.AddDate(0, 0, -1), finishTime.AddDate(0, 0, 1))
+		if err != nil {
+			return false, err
+		}
+
+		window, err := dbs.GetBookingWindow(ctx, facility)
+		if err != nil {
+			return false, err
+		}
+
+		inputError := dbs.Helper.checkDateInput(startTime, finishTime, facility, window, overrides)
+		if inputError != nil {
+			return false, inputError
+		}
+	}
+
+	layoutTime := "2006-01-02 15:04:05"
+	startTimeText := startTime.Format(layoutTime)
+	finishTimeText := finishTime.Format(layoutTime)
+
+	query := `
+	SELECT 
+		(SELECT COUNT(*) 
+		FROM facility_request 
+		WHERE start < ? AND finish > ? 
+		AND facility_id = ? 
+		AND status='APPROVED') + 
+		(SELECT COUNT(*) 
+		FROM facility_busy_block 
+		WHERE start < ? AND finish > ? 
+		AND facility_id = ?);`
+	query = dbs.SQL.Rebind(query)
+	if err := dbs.SQL.GetContext(ctx, &count, query, finishTimeText, startTimeText, facilityID, finishTimeText, startTimeText, facilityID); err != nil {
+		return false, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	return count != 0, nil
+}
+
+// GetBookingWindow is function to get booking window of the facility, each field facility does not configure falls back to organization's default and then to built-in default
+func (dbs *DataService) GetBookingWindow(ctx context.Context, facility *common.Facility) (*common.BookingWindow, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	if facility.BookingWindow.GetMaxAdvanceDays() != 0 && facility.BookingWindow.GetMinLeadMinutes() != 0 {
+		return facility.BookingWindow, nil
+	}
+
+	var window model.BookingWindow
+	query := `
+	SELECT max_advance_days, min_lead_minutes 
+	FROM organization_booking_window 
+	WHERE organization_id = ?`
+	query = dbs.SQL.Rebind(query)
+	if err := dbs.SQL.GetContext(ctx, &window, query, facility.OrganizationId); err != nil && err != sql.ErrNoRows {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	return mergeBookingWindow(facility.BookingWindow, &common.BookingWindow{
+		MaxAdvanceDays: window.MaxAdvanceDays,
+		MinLeadMinutes: window.MinLeadMinutes,
+	}), nil
+}
+
+// SetOrganizationBookingWindow is function to set default booking window of facilities owned by the organization
+func (dbs *DataService) SetOrganizationBookingWindow(ctx context.Context, organizationID int64, window *common.BookingWindow) (*common.BookingWindow, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	if err := checkBookingWindow(window); err != nil {
+		return nil, err
+	}
+
+	query := `
+	INSERT INTO organization_booking_window (organization_id, max_advance_days, min_lead_minutes) 
+	VALUES (?, ?, ?) 
+	ON CONFLICT (organization_id) 
+	DO UPDATE SET max_advance_days = EXCLUDED.max_advance_days, min_lead_minutes = EXCLUDED.min_lead_minutes`
+	query = dbs.SQL.Rebind(query)
+	if _, err := dbs.SQL.ExecContext(ctx, query, organizationID, window.GetMaxAdvanceDays(), window.GetMinLeadMinutes()); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	return &common.BookingWindow{
+		MaxAdvanceDays: window.GetMaxAdvanceDays(),
+		MinLeadMinutes: window.GetMinLeadMinutes(),
+	}, nil
+}
+
+// GetOperatingHourOverrides is function to get operating hour overrides of the facility keyed by date from start to finish date, facility's overrides take precedence over organization's holidays
+func (dbs *DataService) GetOperatingHourOverrides(ctx context.Context, facilityID int64, start time.Time, finish time.Time) (map[string]*common.OperatingHourOverride, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	var overrides []*model.OperatingHourOverride
+	query := `
+	SELECT facility_id, date, is_closed, start_hour, finish_hour, reason 
+	FROM (
+		SELECT f.id AS facility_id, h.date, TRUE AS is_closed, 0 AS start_hour, 0 AS finish_hour, h.name AS reason, 0 AS priority 
+		FROM organization_holiday AS h 
+		INNER JOIN facility AS f 
+		ON f.organization_id = h.organization_id 
+		WHERE f.id = ? AND h.date BETWEEN ? AND ? 
+		UNION ALL 
+		SELECT o.facility_id, o.date, o.is_closed, o.start_hour, o.finish_hour, o.reason, 1 AS priority 
+		FROM facility_operating_hour_override AS o 
+		WHERE o.facility_id = ? AND o.date BETWEEN ? AND ?
+	) AS overrides 
+	ORDER BY date, priority;`
+	query = dbs.SQL.Rebind(query)
+
+	startDateText := start.Format(helper.DateLayout)
+	finishDateText := finish.Format(helper.DateLayout)
+	if err := dbs.SQL.SelectContext(ctx, &overrides, query, facilityID, startDateText, finishDateText, facilityID, startDateText, finishDateText); err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	result := map[string]*common.OperatingHourOverride{}
+	for _, item := range overrides {
+		value := convertOperatingHourOverrideModelToProto(item)
+		result[value.Date] = value
+	}
+
+	return result, nil
+}
+
+// SetOperatingHourOverride is function to create or replace operating hour override of the facility on the date
+func (dbs *DataService) SetOperatingHourOverride(ctx context.Context, data *common.OperatingHourOverride) (*common.OperatingHourOverride, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	if err := checkOperatingHourOverrideInput(data); err != nil {
+		return nil, err
+	}
+
+	query := `
+	INSERT INTO facility_operating_hour_override (facility_id, date, is_closed, start_hour, finish_hour, reason) 
+	VALUES (:facility_id, :date, :is_closed, :start_hour, :finish_hour, :reason) 
+	ON CONFLICT (facility_id, date) 
+	DO UPDATE SET is_closed = EXCLUDED.is_closed, start_hour = EXCLUDED.start_hour, finish_hour = EXCLUDED.finish_hour, reason = EXCLUDED.reason`
+	_, err := dbs.SQL.NamedExecContext(ctx, query, map[string]interface{}{
+		"facility_id": data.FacilityId,
+		"date":        data.Date,
+		"is_closed":   data.IsClosed,
+		"start_hour":  data.StartHour,
+		"finish_hour": data.FinishHour,
+		"reason":      data.Reason,
 	})
+	if err != nil {
+		return nil, &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+
+	return data, nil
+}
+
+// DeleteOperatingHourOverride is function to delete operating hour override of the facility on the date
+func (dbs *DataService) DeleteOperatingHourOverride(ctx context.Context, facilityID int64, date string) typing.CustomError {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+	DELETE FROM facility_operating_hour_override 
+	WHERE facility_id = ? AND date = ?`
+	query = dbs.SQL.Rebind(query)
+	result, err := dbs.SQL.ExecContext(ctx, query, facilityID, date)
 	if err != nil {
 		return &typing.DatabaseError{
 			Err:        err,
@@ -154,7 +1500,7 @@ func (dbs *DataService) updateFacilityRequest(requestID int64, status common.Sta
 		}
 	case count != 1:
 		return &typing.DatabaseError{
-			Err:        &typing.NotFoundError{Name: "FacilityRequest"},
+			Err:        &typing.NotFoundError{Name: "OperatingHourOverride"},
 			StatusCode: codes.NotFound,
 		}
 	default:
@@ -162,106 +1508,99 @@ func (dbs *DataService) updateFacilityRequest(requestID int64, status common.Sta
 	}
 }
 
-// RejectFacilityRequest is a function to reject facility’s request by id
-func (dbs *DataService) RejectFacilityRequest(requestID int64, reason *wrapperspb.StringValue) typing.CustomError {
-	return dbs.updateFacilityRequest(requestID, common.Status_REJECTED, reason)
-}
+// ImportHolidayCalendar is function to replace holidays of the organization's calendar in one transaction
+func (dbs *DataService) ImportHolidayCalendar(ctx context.Context, organizationID int64, calendarName string, holidays []*common.Holiday) typing.CustomError {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
 
-// ApproveFacilityRequest is a function to approve facility request
-func (dbs *DataService) ApproveFacilityRequest(requestID int64) typing.CustomError {
-	return dbs.updateFacilityRequest(requestID, common.Status_APPROVED, nil)
-}
+	for _, holiday := range holidays {
+		if _, err := time.Parse(helper.DateLayout, holiday.Date); err != nil {
+			return &typing.InputError{Name: "Holiday date must be in YYYY-MM-DD format"}
+		}
+	}
 
-// CreateFacilityRequest is a function to create facilityRequest
-func (dbs *DataService) CreateFacilityRequest(eventID int64, facilityID int64, start *timestamppb.Timestamp, finish *timestamppb.Timestamp) (*common.FacilityRequest, typing.CustomError) {
-	var id int64
-	query := `
-	INSERT INTO facility_request (event_id, facility_id, status, start, finish) 
-	VALUES (:event_id, :facility_id, :status, :start, :finish) 
-	RETURNING id`
-	startTime, _ := ptypes.Timestamp(start)
-	finishTime, _ := ptypes.Timestamp(finish)
-	rows, err := dbs.SQL.NamedQuery(query, map[string]interface{}{
-		"event_id":    eventID,
-		"facility_id": facilityID,
-		"status":      "PENDING",
-		"start":       startTime,
-		"finish":      finishTime,
-	})
+	tx, err := dbs.SQL.BeginTxx(ctx, nil)
 	if err != nil {
-		return nil, &typing.DatabaseError{
+		return &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := tx.Rebind(`
+	DELETE FROM organization_holiday 
+	WHERE organization_id = ? AND calendar_name = ?`)
+	if _, err := tx.ExecContext(ctx, query, organizationID, calendarName); err != nil {
+		return &typing.DatabaseError{
 			Err:        err,
 			StatusCode: codes.Internal,
 		}
 	}
-	if rows.Next() {
-		if err := rows.Scan(&id); err != nil {
-			return nil, &typing.DatabaseError{
+
+	query = tx.Rebind(`
+	INSERT INTO organization_holiday (organization_id, calendar_name, date, name) 
+	VALUES (?, ?, ?, ?) 
+	ON CONFLICT (organization_id, calendar_name, date) 
+	DO UPDATE SET name = EXCLUDED.name`)
+	for _, holiday := range holidays {
+		if _, err := tx.ExecContext(ctx, query, organizationID, calendarName, holiday.Date, holiday.Name); err != nil {
+			return &typing.DatabaseError{
 				Err:        err,
 				StatusCode: codes.Internal,
 			}
 		}
 	}
 
-	result := common.FacilityRequest{
-		Id:         id,
-		EventId:    eventID,
-		FacilityId: facilityID,
-		Status:     common.Status_PENDING,
-		Start:      start,
-		Finish:     finish,
-	}
-	return &result, nil
-}
-
-// IsOverlapTime is function to check whether time is overlap with already booked facility
-func (dbs *DataService) IsOverlapTime(facilityID int64, start *timestamppb.Timestamp, finish *timestamppb.Timestamp, checkTimeIntegrity bool) (bool, typing.CustomError) {
-	facility, facilityNotFoundError := dbs.GetFacilityInfo(facilityID)
-	if facilityNotFoundError != nil {
-		return false, facilityNotFoundError
-	}
-
-	var count int64
-	startTime, _ := ptypes.Timestamp(start)
-	finishTime, _ := ptypes.Timestamp(finish)
-	if checkTimeIntegrity {
-		inputError := dbs.Helper.checkDateInput(startTime, finishTime, facility.OperatingHours)
-		if inputError != nil {
-			return false, inputError
+	if err := tx.Commit(); err != nil {
+		return &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
 		}
 	}
 
-	layoutTime := "2006-01-02 15:04:05"
-	startTimeText := startTime.Format(layoutTime)
-	finishTimeText := finishTime.Format(layoutTime)
+	return nil
+}
+
+// GetFacilityRequestHistory is function to get status transitions of facility request, oldest first
+func (dbs *DataService) GetFacilityRequestHistory(ctx context.Context, requestID int64) ([]*facility.FacilityRequestHistory, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
 
+	var history []*model.FacilityRequestHistory
 	query := `
-	SELECT COUNT(*) 
-	FROM facility_request 
-	WHERE ((? >= start AND ? < finish) OR (? > start AND ? <= finish)) 
-	AND facility_id = ? 
-	AND status='APPROVED' 
-	LIMIT 1;`
+	SELECT * 
+	FROM facility_request_history 
+	WHERE facility_request_id = ? 
+	ORDER BY created_at, id`
 	query = dbs.SQL.Rebind(query)
-	if err := dbs.SQL.Get(&count, query, startTimeText, startTimeText, finishTimeText, finishTimeText, facilityID); err != nil {
-		return false, &typing.DatabaseError{
+	if err := dbs.SQL.SelectContext(ctx, &history, query, requestID); err != nil {
+		return nil, &typing.DatabaseError{
 			Err:        err,
 			StatusCode: codes.Internal,
 		}
 	}
 
-	return count != 0, nil
+	result := make([]*facility.FacilityRequestHistory, len(history))
+	for i, item := range history {
+		result[i] = convertFacilityRequestHistoryModelToProto(item)
+	}
+
+	return result, nil
 }
 
 // GetFacilityRequestStatusFull is function to get facilityR request full by id
-func (dbs *DataService) GetFacilityRequestStatusFull(requestID int64) (*facility.FacilityRequestWithFacilityInfo, typing.CustomError) {
+func (dbs *DataService) GetFacilityRequestStatusFull(ctx context.Context, requestID int64) (*facility.FacilityRequestWithFacilityInfo, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
 	var facilityRequest model.FacilityRequestWithInfo
 
 	query := queryForRequestFacilityWithFacilty + `
 	WHERE r.id=?
 	LIMIT 1;`
 	query = dbs.SQL.Rebind(query)
-	err := dbs.SQL.Get(&facilityRequest, query, requestID)
+	err := dbs.SQL.GetContext(ctx, &facilityRequest, query, requestID)
 
 	switch {
 	case err == sql.ErrNoRows:
@@ -280,7 +1619,10 @@ func (dbs *DataService) GetFacilityRequestStatusFull(requestID int64) (*facility
 }
 
 // GetFacilityRequest is function to get facility request by id
-func (dbs *DataService) GetFacilityRequest(requestID int64) (*common.FacilityRequest, typing.CustomError) {
+func (dbs *DataService) GetFacilityRequest(ctx context.Context, requestID int64) (*common.FacilityRequest, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
 	var facilityRequest model.FacilityRequest
 
 	query := `
@@ -289,7 +1631,7 @@ func (dbs *DataService) GetFacilityRequest(requestID int64) (*common.FacilityReq
 	WHERE id=?
 	LIMIT 1;`
 	query = dbs.SQL.Rebind(query)
-	err := dbs.SQL.Get(&facilityRequest, query, requestID)
+	err := dbs.SQL.GetContext(ctx, &facilityRequest, query, requestID)
 
 	switch {
 	case err == sql.ErrNoRows:
@@ -307,55 +1649,82 @@ func (dbs *DataService) GetFacilityRequest(requestID int64) (*common.FacilityReq
 	}
 }
 
-func (dbs *DataService) getFacilityRequestWithFacilityInfoList(condition string, params ...interface{}) ([]*facility.FacilityRequestWithFacilityInfo, typing.CustomError) {
+func (dbs *DataService) getFacilityRequestWithFacilityInfoList(ctx context.Context, conditions []string, params []interface{}, option *facility.FacilityRequestListOption) ([]*facility.FacilityRequestWithFacilityInfo, string, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
 	var facilities []*model.FacilityRequestWithInfo
 
+	condition, params, err := buildFacilityRequestListCondition(conditions, params, option)
+	if err != nil {
+		return nil, "", err
+	}
+
 	query := queryForRequestFacilityWithFacilty + condition
 	query = dbs.SQL.Rebind(query)
-	if err := dbs.SQL.Select(&facilities, query, params...); err != nil {
-		return nil, &typing.DatabaseError{
+	if err := dbs.SQL.SelectContext(ctx, &facilities, query, params...); err != nil {
+		return nil, "", &typing.DatabaseError{
 			Err:        err,
 			StatusCode: codes.Internal,
 		}
 	}
+
+	var nextPageToken string
+	limit := pageSizeOrDefault(option.GetPageSize())
+	if len(facilities) > limit {
+		facilities = facilities[:limit]
+		last := facilities[limit-1]
+		cursor := pageCursor{SortBy: option.GetSortBy(), Descending: option.GetDescending(), Value: last.Start, ID: last.ID}
+		if option.GetSortBy() == facility.SortBy_CREATED_AT {
+			cursor.Value = last.CreatedAt
+		}
+		nextPageToken = encodePageToken(&cursor)
+	}
+
 	result := make([]*facility.FacilityRequestWithFacilityInfo, len(facilities))
 	for i, item := range facilities {
 		value, err := dbs.Helper.convertFacilityRequestWithInfoModelToProto(item)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		result[i] = value
 	}
 
-	return result, nil
+	return result, nextPageToken, nil
 }
 
-// GetFacilityRequestList is a function to get facilityrequest list owned by the organization from database
-func (dbs *DataService) GetFacilityRequestList(organizationID int64) ([]*facility.FacilityRequestWithFacilityInfo, typing.CustomError) {
-	return dbs.getFacilityRequestWithFacilityInfoList(`WHERE organization_id = ?;`, organizationID)
+// GetFacilityRequestList is a function to get a page of facilityrequest list owned by the organization from database
+func (dbs *DataService) GetFacilityRequestList(ctx context.Context, organizationID int64, option *facility.FacilityRequestListOption) ([]*facility.FacilityRequestWithFacilityInfo, string, typing.CustomError) {
+	return dbs.getFacilityRequestWithFacilityInfoList(ctx, []string{"f.organization_id = ?"}, []interface{}{organizationID}, option)
 }
 
-// GetFacilityRequestsListStatus is a function to get facilityrequest list of the event from database
-func (dbs *DataService) GetFacilityRequestsListStatus(eventID int64) ([]*facility.FacilityRequestWithFacilityInfo, typing.CustomError) {
-	return dbs.getFacilityRequestWithFacilityInfoList(`WHERE event_id = ?;`, eventID)
+// GetFacilityRequestsListStatus is a function to get a page of facilityrequest list of the event from database
+func (dbs *DataService) GetFacilityRequestsListStatus(ctx context.Context, eventID int64, option *facility.FacilityRequestListOption) ([]*facility.FacilityRequestWithFacilityInfo, string, typing.CustomError) {
+	return dbs.getFacilityRequestWithFacilityInfoList(ctx, []string{"r.event_id = ?"}, []interface{}{eventID}, option)
 }
 
-// GetApprovedFacilityRequestList is a function to get approved facilityRequestList by facility ID
-func (dbs *DataService) GetApprovedFacilityRequestList(facilityID int64, start *timestamppb.Timestamp, finish *timestamppb.Timestamp) ([]*common.FacilityRequest, typing.CustomError) {
+// GetApprovedFacilityRequestList is a function to get approved facilityRequestList by facility ID covering the days from start to finish in their time zone
+func (dbs *DataService) GetApprovedFacilityRequestList(ctx context.Context, facilityID int64, start time.Time, finish time.Time) ([]*common.FacilityRequest, typing.CustomError) {
+	ctx, cancel := dbs.withTimeout(ctx)
+	defer cancel()
+
 	var facilitieRequests []*model.FacilityRequest
 	query := `
 	SELECT * 
 	FROM facility_request
 	WHERE facility_id = ?
-	AND start BETWEEN ? AND ?
+	AND start < ?
+	AND finish > ?
 	AND status = 'APPROVED';`
 	query = dbs.SQL.Rebind(query)
 
-	layoutTime := "2006-01-02"
-	startTimeText := helper.TimeStampToText(start, layoutTime)
-	finishTimeText := helper.TimeStampToText(finish, layoutTime)
+	layoutTime := "2006-01-02 15:04:05"
+	windowStart := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	windowFinish := time.Date(finish.Year(), finish.Month(), finish.Day()+1, 0, 0, 0, 0, finish.Location())
+	startTimeText := windowStart.UTC().Format(layoutTime)
+	finishTimeText := windowFinish.UTC().Format(layoutTime)
 
-	if err := dbs.SQL.Select(&facilitieRequests, query, facilityID, startTimeText, finishTimeText); err != nil {
+	if err := dbs.SQL.SelectContext(ctx, &facilitieRequests, query, facilityID, finishTimeText, startTimeText); err != nil {
 		return nil, &typing.DatabaseError{
 			Err:        err,
 			StatusCode: codes.Internal,
@@ -387,6 +1756,12 @@ func (dbs *DataService) ConnectToDB() {
 	password := os.Getenv("POSTGRES_PASSWORD")
 	database := os.Getenv("POSTGRES_DB")
 	port := os.Getenv("POSTGRES_PORT")
+	if timeout := os.Getenv("POSTGRES_TIMEOUT"); timeout != "" {
+		var err error
+		if dbs.Timeout, err = time.ParseDuration(timeout); err != nil {
+			log.Fatalln(err)
+		}
+	}
 	dsn := fmt.Sprintf("user=%s password=%s host=%s database=%s port=%s sslmode=disable", user, password, host, database, port)
 	db, err := sqlx.Connect("postgres", dsn)
 
@@ -397,6 +1772,7 @@ func (dbs *DataService) ConnectToDB() {
 	strcase.ConfigureAcronym("ID", "id")
 	db.Mapper = reflectx.NewMapperFunc("json", strcase.ToSnake)
 	dbs.SQL = db
+	dbs.listenFacilityRequestChanges(dsn)
 	version, err := dbs.ping()
 	if err == nil {
 		log.Println("SQL version:", version)
@@ -1,10 +1,15 @@
 package database
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	common "onepass.app/facility/hts/common"
+	facility "onepass.app/facility/hts/facility"
 )
 
 func TestSomething4(t *testing.T) {
@@ -19,3 +24,23 @@ func TestSomething4(t *testing.T) {
 	assert.Empty(t, a, "A is empty")
 	// log.Println(a)
 }
+
+func TestFindAvailableFacilitiesInput(t *testing.T) {
+	assert := assert.New(t)
+	dbs := &DataService{}
+
+	start := time.Date(2021, time.March, 2, 13, 0, 0, 0, time.UTC)
+	var tests = []*facility.FindAvailableFacilitiesRequest{
+		{},
+		{Start: timestamppb.New(start)},
+		{Start: timestamppb.New(start), End: timestamppb.New(start)},
+		{Start: timestamppb.New(start), End: timestamppb.New(start.Add(3 * time.Hour)), Latitude: 91, RadiusMeters: 1000},
+		{Start: timestamppb.New(start), End: timestamppb.New(start.Add(3 * time.Hour)), RadiusMeters: -1},
+	}
+
+	for _, test := range tests {
+		_, err := dbs.FindAvailableFacilities(context.Background(), test)
+		assert.NotNil(err)
+		assert.Equal(codes.InvalidArgument, err.Code())
+	}
+}
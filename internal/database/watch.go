@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"google.golang.org/grpc/codes"
+
+	model "onepass.app/facility/internal/model"
+	typing "onepass.app/facility/internal/typing"
+)
+
+// facilityRequestChannel is postgres channel notified when a facility request is created or its status is changed
+const facilityRequestChannel = "facility_request_change"
+
+// subscriberBufferSize is number of changes a subscriber can fall behind before it is dropped
+const subscriberBufferSize = 64
+
+// facilityRequestWatcher is for fanning out changes notified by postgres to subscribers of this replica
+type facilityRequestWatcher struct {
+	mutex       sync.Mutex
+	subscribers map[chan *model.FacilityRequestChange]bool
+}
+
+func (watcher *facilityRequestWatcher) subscribe() chan *model.FacilityRequestChange {
+	subscriber := make(chan *model.FacilityRequestChange, subscriberBufferSize)
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	watcher.subscribers[subscriber] = true
+	return subscriber
+}
+
+func (watcher *facilityRequestWatcher) unsubscribe(subscriber chan *model.FacilityRequestChange) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	if watcher.subscribers[subscriber] {
+		delete(watcher.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+func (watcher *facilityRequestWatcher) broadcast(change *model.FacilityRequestChange) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	for subscriber := range watcher.subscribers {
+		select {
+		case subscriber <- change:
+		default:
+			// a subscriber too slow to keep up is closed instead of blocking the others
+			delete(watcher.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// notifyFacilityRequestChanges is function to notify every replica about the facility requests, inside a transaction it is sent on commit
+func notifyFacilityRequestChanges(ctx context.Context, execer sqlx.ExecerContext, requestIDs []int64) typing.CustomError {
+	query := sqlx.Rebind(sqlx.DOLLAR, `
+	SELECT pg_notify(?, CAST(json_build_object(
+		'id', r.id,
+		'event_id', r.event_id,
+		'facility_id', r.facility_id,
+		'organization_id', f.organization_id,
+		'status', r.status
+	) AS text))
+	FROM facility_request AS r
+	INNER JOIN facility AS f
+	ON f.id = r.facility_id
+	WHERE r.id = ANY(?)`)
+	if _, err := execer.ExecContext(ctx, query, facilityRequestChannel, pq.Array(requestIDs)); err != nil {
+		return &typing.DatabaseError{
+			Err:        err,
+			StatusCode: codes.Internal,
+		}
+	}
+	return nil
+}
+
+// listenFacilityRequestChanges is function to start listening to facility request changes of every replica
+func (dbs *DataService) listenFacilityRequestChanges(dsn string) {
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println("Facility request listener:", err)
+		}
+	})
+	if err := listener.Listen(facilityRequestChannel); err != nil {
+		log.Fatalln(err)
+	}
+
+	dbs.watcher = &facilityRequestWatcher{subscribers: map[chan *model.FacilityRequestChange]bool{}}
+	go func() {
+		for notification := range listener.Notify {
+			// nil is sent after the connection is re-established, changes during the outage are lost
+			if notification == nil {
+				log.Println("Facility request listener: reconnected")
+				continue
+			}
+
+			var change model.FacilityRequestChange
+			if err := json.Unmarshal([]byte(notification.Extra), &change); err != nil {
+				log.Println("Facility request listener:", err)
+				continue
+			}
+			dbs.watcher.broadcast(&change)
+		}
+	}()
+}
+
+// SubscribeFacilityRequestChanges is function to receive every facility request change until unsubscribe is called, the channel is closed when the subscriber falls behind
+func (dbs *DataService) SubscribeFacilityRequestChanges() (<-chan *model.FacilityRequestChange, func(), typing.CustomError) {
+	if dbs.watcher == nil {
+		return nil, nil, &typing.GRPCError{Name: "Facility request listener"}
+	}
+
+	subscriber := dbs.watcher.subscribe()
+	return subscriber, func() { dbs.watcher.unsubscribe(subscriber) }, nil
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	model "onepass.app/facility/internal/model"
+)
+
+func TestFacilityRequestWatcher(t *testing.T) {
+	assert := assert.New(t)
+	dbs := &DataService{}
+
+	_, _, err := dbs.SubscribeFacilityRequestChanges()
+	assert.NotNil(err)
+
+	dbs.watcher = &facilityRequestWatcher{subscribers: map[chan *model.FacilityRequestChange]bool{}}
+	fast, unsubscribeFast, err := dbs.SubscribeFacilityRequestChanges()
+	assert.Nil(err)
+	slow, _, err := dbs.SubscribeFacilityRequestChanges()
+	assert.Nil(err)
+
+	for i := 1; i <= subscriberBufferSize; i++ {
+		dbs.watcher.broadcast(&model.FacilityRequestChange{ID: int64(i)})
+		assert.Equal(int64(i), (<-fast).ID)
+	}
+
+	// slow has not received anything, so it is closed once its buffer is full
+	dbs.watcher.broadcast(&model.FacilityRequestChange{ID: subscriberBufferSize + 1})
+	assert.Equal(int64(subscriberBufferSize+1), (<-fast).ID)
+	for i := 1; i <= subscriberBufferSize; i++ {
+		assert.Equal(int64(i), (<-slow).ID)
+	}
+	_, ok := <-slow
+	assert.False(ok)
+
+	unsubscribeFast()
+	_, ok = <-fast
+	assert.False(ok)
+	assert.Empty(dbs.watcher.subscribers)
+}
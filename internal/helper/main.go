@@ -1,12 +1,24 @@
 package helper
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/timestamp"
+	common "onepass.app/facility/hts/common"
+	typing "onepass.app/facility/internal/typing"
 )
 
+// DefaultSlotMinutes is booking granularity of a facility when it is not configured
+const DefaultSlotMinutes = 60
+
+// DefaultMaxAdvanceDays is how many days ahead a facility is bookable when it is not configured
+const DefaultMaxAdvanceDays = 30
+
+// DateLayout is layout of date used by operating hour overrides and holidays
+const DateLayout = "2006-01-02"
+
 // DayDifferenceFunc is type for DayDifference function
 type DayDifferenceFunc func(start time.Time, end time.Time) int
 
@@ -35,3 +47,113 @@ func TimeStampToText(time *timestamp.Timestamp, layout string) string {
 	timeDate, _ := ptypes.Timestamp(time)
 	return timeDate.Format(layout)
 }
+
+// SlotMinutesOrDefault is a function to get facility booking granularity in minutes
+func SlotMinutesOrDefault(slotMinutes int64) int64 {
+	if slotMinutes <= 0 {
+		return DefaultSlotMinutes
+	}
+	return slotMinutes
+}
+
+// MaxAdvanceDaysOrDefault is a function to get how many days ahead the facility is bookable
+func MaxAdvanceDaysOrDefault(window *common.BookingWindow) int {
+	if window.GetMaxAdvanceDays() <= 0 {
+		return DefaultMaxAdvanceDays
+	}
+	return int(window.GetMaxAdvanceDays())
+}
+
+// CheckAdvanceWindow is a function to check if booking finishes within the days of booking window from today
+func CheckAdvanceWindow(now time.Time, finish time.Time, window *common.BookingWindow) typing.CustomError {
+	maxAdvanceDays := MaxAdvanceDaysOrDefault(window)
+	if DayDifference(now, finish) >= maxAdvanceDays {
+		return &typing.InputError{Name: fmt.Sprintf("Booking date can only be within %d days period from today", maxAdvanceDays)}
+	}
+	return nil
+}
+
+// CheckLeadTime is a function to check if booking starts at least the lead time of booking window from now
+func CheckLeadTime(now time.Time, start time.Time, window *common.BookingWindow) typing.CustomError {
+	minLeadMinutes := window.GetMinLeadMinutes()
+	if minLeadMinutes > 0 && start.Before(now.Add(time.Duration(minLeadMinutes)*time.Minute)) {
+		return &typing.InputError{Name: fmt.Sprintf("Booking must start at least %d minutes from now", minLeadMinutes)}
+	}
+	return nil
+}
+
+// TimeOfDay is a function to get duration elapsed since midnight of the time
+func TimeOfDay(t time.Time) time.Duration {
+	hour, minute, second := t.Clock()
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second
+}
+
+// TimeZoneOrDefault is a function to get facility IANA time zone, UTC when it is not configured
+func TimeZoneOrDefault(timeZone string) string {
+	if timeZone == "" {
+		return time.UTC.String()
+	}
+	return timeZone
+}
+
+// WeekStart is a function to get midnight of Monday of the week in the time's location
+func WeekStart(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+}
+
+// OverlapMinutes is a function to get minutes of the first interval overlapping with the second one
+func OverlapMinutes(start time.Time, finish time.Time, otherStart time.Time, otherFinish time.Time) int64 {
+	if start.Before(otherStart) {
+		start = otherStart
+	}
+	if finish.After(otherFinish) {
+		finish = otherFinish
+	}
+	if !start.Before(finish) {
+		return 0
+	}
+	return int64(finish.Sub(start) / time.Minute)
+}
+
+// OperatingHourOfDay is a function to get operating hour of the day, date-specific override is consulted before weekly schedule and nil means closed
+func OperatingHourOfDay(day time.Time, operatingHours []*common.OperatingHour, overrides map[string]*common.OperatingHourOverride) *common.OperatingHour {
+	if override, ok := overrides[day.Format(DateLayout)]; ok {
+		if override.IsClosed {
+			return nil
+		}
+		return &common.OperatingHour{
+			Day:        common.DayOfWeek(day.Weekday()),
+			StartHour:  override.StartHour,
+			FinishHour: override.FinishHour,
+		}
+	}
+
+	var operatingHour *common.OperatingHour
+	for _, value := range operatingHours {
+		if int(value.Day.Number()) == int(day.Weekday()) {
+			operatingHour = value
+		}
+	}
+	return operatingHour
+}
+
+// ApproverStage is a function to get index of the first stage of approval chain the user approves for, -1 means the user is not an approver
+func ApproverStage(chain *common.ApprovalChain, userID int64) int32 {
+	for i, stage := range chain.GetStages() {
+		if IsStageApprover(stage, userID) {
+			return int32(i)
+		}
+	}
+	return -1
+}
+
+// IsStageApprover is a function to check if the user approves for the stage
+func IsStageApprover(stage *common.ApprovalStage, userID int64) bool {
+	for _, approverID := range stage.ApproverIds {
+		if approverID == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/stretchr/testify/assert"
+	"onepass.app/facility/hts/common"
 )
 
 func TestDayDifference(t *testing.T) {
@@ -79,3 +80,76 @@ func TestTimeStampToText(t *testing.T) {
 		assert.Equal(test.expected, text, "timestamp should be correct")
 	}
 }
+
+func TestSlotMinutesOrDefault(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(int64(DefaultSlotMinutes), SlotMinutesOrDefault(0))
+	assert.Equal(int64(DefaultSlotMinutes), SlotMinutesOrDefault(-15))
+	assert.Equal(int64(15), SlotMinutesOrDefault(15))
+}
+
+func TestTimeOfDay(t *testing.T) {
+	assert := assert.New(t)
+
+	mockTime := time.Date(2021, time.February, 24, 13, 45, 30, 0, time.UTC)
+	assert.Equal(13*time.Hour+45*time.Minute+30*time.Second, TimeOfDay(mockTime))
+	assert.Equal(time.Duration(0), TimeOfDay(time.Date(2021, time.February, 24, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestTimeZoneOrDefault(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("UTC", TimeZoneOrDefault(""))
+	assert.Equal("Asia/Bangkok", TimeZoneOrDefault("Asia/Bangkok"))
+}
+
+func TestOperatingHourOfDay(t *testing.T) {
+	assert := assert.New(t)
+
+	monday := time.Date(2021, time.February, 22, 10, 0, 0, 0, time.UTC)
+	operatingHours := []*common.OperatingHour{{Day: common.DayOfWeek_MON, StartHour: 9, FinishHour: 18}}
+
+	assert.Equal(int64(9), OperatingHourOfDay(monday, operatingHours, nil).StartHour)
+	assert.Nil(OperatingHourOfDay(monday.AddDate(0, 0, 1), operatingHours, nil))
+
+	overrides := map[string]*common.OperatingHourOverride{"2021-02-22": {Date: "2021-02-22", IsClosed: true}}
+	assert.Nil(OperatingHourOfDay(monday, operatingHours, overrides))
+
+	overrides = map[string]*common.OperatingHourOverride{"2021-02-23": {Date: "2021-02-23", StartHour: 13, FinishHour: 15}}
+	operatingHour := OperatingHourOfDay(monday.AddDate(0, 0, 1), operatingHours, overrides)
+	assert.Equal(common.DayOfWeek_TUE, operatingHour.Day)
+	assert.Equal(int64(13), operatingHour.StartHour)
+	assert.Equal(int64(15), operatingHour.FinishHour)
+}
+
+func TestApproverStage(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := &common.ApprovalChain{Stages: []*common.ApprovalStage{{ApproverIds: []int64{1, 2}}, {ApproverIds: []int64{3}}}}
+	assert.Equal(int32(0), ApproverStage(chain, 2))
+	assert.Equal(int32(1), ApproverStage(chain, 3))
+	assert.Equal(int32(-1), ApproverStage(chain, 4))
+	assert.Equal(int32(-1), ApproverStage(nil, 1))
+}
+
+func TestWeekStart(t *testing.T) {
+	assert := assert.New(t)
+
+	location, _ := time.LoadLocation("Asia/Bangkok")
+	sunday := time.Date(2021, time.February, 28, 23, 30, 0, 0, location)
+	assert.Equal(time.Date(2021, time.February, 22, 0, 0, 0, 0, location), WeekStart(sunday))
+	monday := time.Date(2021, time.March, 1, 0, 0, 0, 0, location)
+	assert.Equal(monday, WeekStart(monday))
+	assert.Equal(monday, WeekStart(monday.Add(36*time.Hour)))
+}
+
+func TestOverlapMinutes(t *testing.T) {
+	assert := assert.New(t)
+
+	weekStart := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	weekFinish := weekStart.AddDate(0, 0, 7)
+	assert.Equal(int64(90), OverlapMinutes(weekStart.Add(9*time.Hour), weekStart.Add(10*time.Hour+30*time.Minute), weekStart, weekFinish))
+	assert.Equal(int64(60), OverlapMinutes(weekFinish.Add(-time.Hour), weekFinish.Add(time.Hour), weekStart, weekFinish))
+	assert.Equal(int64(0), OverlapMinutes(weekFinish, weekFinish.Add(time.Hour), weekStart, weekFinish))
+}
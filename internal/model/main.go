@@ -23,6 +23,67 @@ type Facility struct {
 	Longitude      float64
 	OperatingHours types.JSONText
 	Description    string
+	SlotMinutes    int64
+	TimeZone       string
+	Capacity       int64
+	Attributes     types.JSONText
+	ApprovalChain  types.JSONText
+	Quota          types.JSONText
+	BookingWindow  types.JSONText
+}
+
+// BookingWindow is struct for facility bookingWindow and model for organization default booking window
+type BookingWindow struct {
+	MaxAdvanceDays int32 `json:"max_advance_days"`
+	MinLeadMinutes int32 `json:"min_lead_minutes"`
+}
+
+// FacilityQuota is struct for facility quota of each requesting organization
+type FacilityQuota struct {
+	MaxHoursPerWeek    int32 `json:"max_hours_per_week"`
+	MaxPendingRequests int32 `json:"max_pending_requests"`
+}
+
+// QuotaUsage is model for usage of facility quota by a requesting organization
+type QuotaUsage struct {
+	BookedMinutes   int64
+	PendingRequests int64
+}
+
+// ApprovalChain is struct for facility approvalChain
+type ApprovalChain struct {
+	Mode   string           `json:"mode"`
+	Stages []*ApprovalStage `json:"stages"`
+	Quorum int32            `json:"quorum"`
+}
+
+// ApprovalStage is struct for stage of facility approvalChain
+type ApprovalStage struct {
+	Name        string  `json:"name"`
+	ApproverIDs []int64 `json:"approver_ids"`
+}
+
+// FacilityAttributes is struct for facility attributes
+type FacilityAttributes struct {
+	IsOutdoor              bool `json:"is_outdoor"`
+	HasProjector           bool `json:"has_projector"`
+	IsWheelchairAccessible bool `json:"is_wheelchair_accessible"`
+}
+
+// FacilityWithDistance is model for facility with great-circle distance in meters from a point
+type FacilityWithDistance struct {
+	Facility
+	Distance float64
+}
+
+// OperatingHourOverride is model for database, holidays are read as closed overrides
+type OperatingHourOverride struct {
+	FacilityID int64
+	Date       time.Time
+	IsClosed   bool
+	StartHour  int64
+	FinishHour int64
+	Reason     string
 }
 
 // FacilityRequest is model for database
@@ -34,22 +95,89 @@ type FacilityRequest struct {
 	RejectReason sql.NullString
 	Start        time.Time
 	Finish       time.Time
+	CancelledBy  sql.NullInt64
+	CancelledAt  sql.NullTime
+	SeriesID     sql.NullInt64
+	CreatedAt    time.Time
+	// RequesterOrganizationID is organization of the event when the request is created
+	RequesterOrganizationID int64
 }
 
 // FacilityRequestWithInfo is joint model between Facility and FacilityRequest for database
 type FacilityRequestWithInfo struct {
+	ID                      int64
+	EventID                 int64
+	FacilityID              int64
+	Status                  string
+	RejectReason            sql.NullString
+	Start                   time.Time
+	Finish                  time.Time
+	CancelledBy             sql.NullInt64
+	CancelledAt             sql.NullTime
+	SeriesID                sql.NullInt64
+	CreatedAt               time.Time
+	RequesterOrganizationID int64
+	FaciltiyID              int64
+	OrganizationID          int64
+	FacilityName            string
+	Latitude                float64
+	Longitude               float64
+	OperatingHours          types.JSONText
+	Description             string
+	SlotMinutes             int64
+	TimeZone                string
+	Capacity                int64
+	Attributes              types.JSONText
+}
+
+// FacilityRequestHistory is model for database, a status transition of facility request
+type FacilityRequestHistory struct {
+	ID                int64
+	FacilityRequestID int64
+	ActorID           int64
+	OldStatus         string
+	NewStatus         string
+	Reason            sql.NullString
+	CreatedAt         time.Time
+}
+
+// ApprovalDecision is model for database, a decision of an approver for a stage of facility approvalChain
+type ApprovalDecision struct {
+	FacilityRequestID int64
+	Stage             int32
+	ApproverID        int64
+	Decision          string
+	Reason            sql.NullString
+	CreatedAt         time.Time
+}
+
+// CalendarFeed is model for database, only hash of the feed token is stored
+type CalendarFeed struct {
 	ID             int64
-	EventID        int64
-	FacilityID     int64
-	Status         string
-	RejectReason   sql.NullString
-	Start          time.Time
-	Finish         time.Time
-	FaciltiyID     int64
 	OrganizationID int64
-	FacilityName   string
-	Latitude       float64
-	Longitude      float64
-	OperatingHours types.JSONText
-	Description    string
+	FacilityID     sql.NullInt64
+	TokenHash      string
+	CreatedBy      int64
+	CreatedAt      time.Time
+	RevokedAt      sql.NullTime
+}
+
+// BusyBlock is model for database, it is imported from external calendar and blocks the facility like approved request
+type BusyBlock struct {
+	ID         int64
+	FacilityID int64
+	Source     string
+	UID        string
+	Summary    string
+	Start      time.Time
+	Finish     time.Time
+}
+
+// FacilityRequestChange is payload notified when facility request is created or its status is changed
+type FacilityRequestChange struct {
+	ID             int64  `json:"id"`
+	EventID        int64  `json:"event_id"`
+	FacilityID     int64  `json:"facility_id"`
+	OrganizationID int64  `json:"organization_id"`
+	Status         string `json:"status"`
 }
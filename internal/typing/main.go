@@ -1,6 +1,10 @@
 package typing
 
 import (
+	"context"
+	"errors"
+	"strconv"
+
 	"google.golang.org/grpc/codes"
 	"onepass.app/facility/hts/common"
 )
@@ -19,8 +23,25 @@ type DatabaseError struct {
 
 func (e *DatabaseError) Error() string { return e.Err.Error() }
 
-// Code is for getting code
-func (e *DatabaseError) Code() codes.Code { return e.StatusCode }
+// Code is for getting code, a call which is cancelled or timed out reports that instead
+func (e *DatabaseError) Code() codes.Code {
+	if code := contextCode(e.Err); code != codes.OK {
+		return code
+	}
+	return e.StatusCode
+}
+
+// contextCode is function to get code of error caused by context, OK means it is not
+func contextCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	default:
+		return codes.OK
+	}
+}
 
 // NotFoundError is a error for not found
 type NotFoundError struct {
@@ -71,3 +92,82 @@ func (e *GRPCError) Error() string { return "service error: " + e.Name }
 
 // Code is for getting code
 func (e *GRPCError) Code() codes.Code { return codes.Unavailable }
+
+// CapacityError is error for event expecting more attendees than facility's capacity
+type CapacityError struct {
+	Capacity   int64
+	Attendance int64
+}
+
+func (e *CapacityError) Error() string {
+	return "capacity error: expected attendance " + strconv.FormatInt(e.Attendance, 10) + " exceeds capacity " + strconv.FormatInt(e.Capacity, 10)
+}
+
+// Code is for getting code
+func (e *CapacityError) Code() codes.Code { return codes.FailedPrecondition }
+
+// TransitionError is error for status change not allowed from the current status
+type TransitionError struct {
+	From common.Status
+	To   common.Status
+}
+
+func (e *TransitionError) Error() string {
+	return "transition error: " + e.From.String() + " request cannot be changed to " + e.To.String()
+}
+
+// Code is for getting code
+func (e *TransitionError) Code() codes.Code { return codes.FailedPrecondition }
+
+// QuotaError is error for request exceeding facility's quota of the requesting organization
+type QuotaError struct {
+	Name  string
+	Limit int64
+	Usage int64
+}
+
+func (e *QuotaError) Error() string {
+	return "quota error: " + e.Name + " would be " + strconv.FormatInt(e.Usage, 10) + " exceeding " + strconv.FormatInt(e.Limit, 10)
+}
+
+// Code is for getting code
+func (e *QuotaError) Code() codes.Code { return codes.ResourceExhausted }
+
+// AuthenticationError is error for missing or invalid token of the caller
+type AuthenticationError struct {
+	Name string
+}
+
+func (e *AuthenticationError) Error() string { return "authentication error: " + e.Name }
+
+// Code is for getting code
+func (e *AuthenticationError) Code() codes.Code { return codes.Unauthenticated }
+
+// IdentityError is error for request acting as other user than the authenticated one
+type IdentityError struct {
+	UserID      int64
+	TokenUserID int64
+}
+
+func (e *IdentityError) Error() string {
+	return "identity error: user ID " + strconv.FormatInt(e.UserID, 10) + " does not match authenticated user ID " + strconv.FormatInt(e.TokenUserID, 10)
+}
+
+// Code is for getting code
+func (e *IdentityError) Code() codes.Code { return codes.PermissionDenied }
+
+// ContextError is error for call to other service which is cancelled or timed out, StatusCode is Canceled or DeadlineExceeded
+type ContextError struct {
+	Name       string
+	StatusCode codes.Code
+}
+
+func (e *ContextError) Error() string {
+	if e.StatusCode == codes.Canceled {
+		return "context error: " + e.Name + " call is cancelled"
+	}
+	return "context error: " + e.Name + " call is timed out"
+}
+
+// Code is for getting code
+func (e *ContextError) Code() codes.Code { return e.StatusCode }